@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers returned by SET GLOBAL that have an explanation.
+const (
+	errUnknownSystemVariable = 1193
+	errSpecificAccessDenied  = 1227
+	errGlobalVariable        = 1229
+	errWrongValueForVar      = 1231
+	errWrongTypeForVar       = 1232
+	errIncorrectGlobalVar    = 1238
+)
+
+// Human explanations of the MySQL errors SET GLOBAL commonly fails with.
+var applyErrorExplanations = map[uint16]string{
+	errUnknownSystemVariable: "the server has no such variable, e.g. because it was removed " +
+		"or its plugin is not loaded",
+	errSpecificAccessDenied: "access denied: the user needs the SYSTEM_VARIABLES_ADMIN " +
+		"or SUPER privilege to set global variables",
+	errGlobalVariable:     "the variable is global only",
+	errWrongValueForVar:   "the server rejected the value for this variable",
+	errWrongTypeForVar:    "the value has the wrong type for this variable",
+	errIncorrectGlobalVar: "the variable is read-only and can only be set at startup, e.g. in my.cnf",
+}
+
+// Records why applying the config value of an option failed: the status,
+// the MySQL error number if the server returned one, and an explanation.
+// Settings that fail validation are skipped without reaching the server,
+// and read-only variables get their own status, since no privilege or
+// value would make the change succeed, whether the server or the metadata
+// reports them as read-only.
+func recordApplyError(result *OptionResult, err error) {
+	result.Status = StatusFailed
+	result.ApplyError = err.Error()
+	var notDynamic *notDynamicError
+	if errors.As(err, &notDynamic) {
+		result.Status = StatusReadOnly
+		result.ApplyExplanation = applyErrorExplanations[errIncorrectGlobalVar]
+		return
+	}
+	var invalid *invalidSettingError
+	if errors.As(err, &invalid) {
+		result.Status = StatusSkipped
+		result.ApplyExplanation = "the setting was not sent to the server"
+		return
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return
+	}
+	result.ApplyErrorNumber = mysqlErr.Number
+	result.ApplyExplanation = applyErrorExplanations[mysqlErr.Number]
+	if mysqlErr.Number == errIncorrectGlobalVar {
+		result.Status = StatusReadOnly
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordApplyError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus DiffStatus
+		expectedNumber uint16
+		explained      bool
+	}{
+		{
+			"when the variable is read-only",
+			&mysql.MySQLError{Number: 1238, Message: "Variable 'datadir' is a read only variable"},
+			StatusReadOnly, 1238, true,
+		},
+		{
+			"when access is denied",
+			fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1227, Message: "Access denied"}),
+			StatusFailed, 1227, true,
+		},
+		{
+			"when the error number is not explained",
+			&mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"},
+			StatusFailed, 1064, false,
+		},
+		{"when the setting is invalid", &invalidSettingError{"invalid key: A B"}, StatusSkipped, 0, true},
+		{"when the variable is not dynamic", &notDynamicError{"DATADIR"}, StatusReadOnly, 0, true},
+		{"when the connection failed", errors.New("driver: bad connection"), StatusFailed, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &OptionResult{Status: StatusDifferent}
+			recordApplyError(result, tt.err)
+			require.Equal(t, tt.expectedStatus, result.Status)
+			require.Equal(t, tt.err.Error(), result.ApplyError)
+			require.Equal(t, tt.expectedNumber, result.ApplyErrorNumber)
+			require.Equal(t, tt.explained, result.ApplyExplanation != "")
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "fmt"
+
+// applyMode is how changes are applied to the server, set with
+// --apply-mode.
+type applyMode string
+
+const (
+	// applyModeGlobal changes the runtime value with SET GLOBAL. The
+	// change is lost when the server restarts.
+	applyModeGlobal applyMode = "global"
+	// applyModePersist changes the runtime value with SET PERSIST, which
+	// also saves it to mysqld-auto.cnf for the next restart.
+	applyModePersist applyMode = "persist"
+	// applyModePersistOnly only saves the value to mysqld-auto.cnf with
+	// SET PERSIST_ONLY, so that it takes effect at the next restart. This
+	// works for read-only variables too.
+	applyModePersistOnly applyMode = "persist-only"
+)
+
+// The apply modes supported by --apply-mode.
+var applyModes = []applyMode{applyModeGlobal, applyModePersist, applyModePersistOnly}
+
+// Parses the value of --apply-mode.
+func parseApplyMode(value string) (applyMode, error) {
+	for _, mode := range applyModes {
+		if value == string(mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --apply-mode: %s", value)
+}
+
+// Returns the keyword of the SET statement for the mode, e.g. PERSIST for
+// `SET PERSIST`. The zero value is treated as global.
+func (m applyMode) keyword() string {
+	switch m {
+	case applyModePersist:
+		return "PERSIST"
+	case applyModePersistOnly:
+		return "PERSIST_ONLY"
+	default:
+		return "GLOBAL"
+	}
+}
+
+// Returns the mode with the given SET statement keyword.
+func applyModeFromKeyword(keyword string) (applyMode, error) {
+	for _, mode := range applyModes {
+		if keyword == mode.keyword() {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown SET keyword: %s", keyword)
+}
+
+// Checks that the server version supports the mode. SET PERSIST and
+// SET PERSIST_ONLY were added in MySQL 8.0, and MariaDB has neither.
+func (m applyMode) checkSupported(version MySQLVersion) error {
+	if m != applyModePersist && m != applyModePersistOnly {
+		return nil
+	}
+	if version.Flavor == FlavorMariaDB {
+		return fmt.Errorf("--apply-mode %s is not supported by MariaDB", m)
+	}
+	if version.Major < 8 {
+		return fmt.Errorf("--apply-mode %s requires MySQL 8.0 or later, the server runs %s", m, version)
+	}
+	return nil
+}
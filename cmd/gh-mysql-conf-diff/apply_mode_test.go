@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseApplyMode(t *testing.T) {
+	mode, err := parseApplyMode("persist-only")
+	require.NoError(t, err)
+	require.Equal(t, applyModePersistOnly, mode)
+	require.Equal(t, "PERSIST_ONLY", mode.keyword())
+
+	_, err = parseApplyMode("session")
+	require.EqualError(t, err, "invalid --apply-mode: session")
+}
+
+func TestApplyModeCheckSupported(t *testing.T) {
+	mysql57 := MySQLVersion{Major: 5, Minor: 7, Patch: 44}
+	mysql80 := MySQLVersion{Major: 8, Minor: 0, Patch: 28}
+
+	require.NoError(t, applyModeGlobal.checkSupported(mysql57))
+	require.NoError(t, applyModePersist.checkSupported(mysql80))
+	require.NoError(t, applyModePersistOnly.checkSupported(mysql80))
+	require.EqualError(t, applyModePersist.checkSupported(mysql57),
+		"--apply-mode persist requires MySQL 8.0 or later, the server runs 5.7.44")
+
+	mariaDB := MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}
+	require.NoError(t, applyModeGlobal.checkSupported(mariaDB))
+	require.EqualError(t, applyModePersistOnly.checkSupported(mariaDB),
+		"--apply-mode persist-only is not supported by MariaDB")
+}
@@ -12,17 +12,47 @@ import (
 
 // RunContext contains the information needed to run the program.
 type RunContext struct {
+	// command is empty for a diff, or the name of another command, e.g.
+	// rollback.
+	command           string
 	configPath        string
+	defaultsFile      string
+	defaultsExtraFile string
 	serverAndPort     string
-	optionKeysToWatch map[string]any
-	applyTheChanges   bool
+	snapshotFile      string
+	// otherServerAndPort is the second server of diff-servers.
+	otherServerAndPort string
+	variablesToSkip    []string
+	optionGroups       OptionGroups
+	persistedConfig    string
+	reportSession      bool
+	outputFormat       string
+	orderBy            string
+	emitSQL            string
+	rollbackFile       string
+	applyMode          applyMode
+	optionKeysToWatch  map[string]any
+	applyTheChanges    bool
 }
 
 // InputContext contains the information from the command-line arguments.
 type InputContext struct {
-	optionsToWatchFlag []string
-	executeFlag        bool
-	helpFlag           bool
+	optionsToWatchFlag    []string
+	executeFlag           bool
+	defaultsFileFlag      string
+	defaultsExtraFileFlag string
+	optionGroupsFlag      []string
+	groupSuffixFlag       string
+	versionGroupsFlag     bool
+	persistedConfigFlag   string
+	reportSessionFlag     bool
+	formatFlag            string
+	orderByFlag           string
+	emitSQLFlag           string
+	rollbackFileFlag      string
+	applyModeFlag         string
+	skipVariablesFlag     []string
+	helpFlag              bool
 
 	positionals []string
 
@@ -37,6 +67,42 @@ func newInputContext() *InputContext {
 		"A comma-separated list of MySQL config file option names to watch")
 	cli.flagset.BoolVarP(&cli.executeFlag, "apply-changes", "", false,
 		"If provided, actually apply the changes discovered. [optional]")
+	cli.flagset.StringVarP(&cli.defaultsFileFlag, "defaults-file", "", "",
+		"Read only the given option file instead of the default option files [optional]")
+	cli.flagset.StringVarP(&cli.defaultsExtraFileFlag, "defaults-extra-file", "", "",
+		"Read the given option file after the global option files but before ~/.my.cnf [optional]")
+	cli.flagset.StringSliceVarP(&cli.optionGroupsFlag, "option-groups", "", nil,
+		"A comma-separated list of option groups to read, in mysqld's order. "+
+			"X.Y is replaced by the server's MAJOR.MINOR version. Defaults to the groups "+
+			"the server reads, e.g. mysqld,server,mysqld-X.Y for MySQL [optional]")
+	cli.flagset.StringVarP(&cli.groupSuffixFlag, "group-suffix", "", "",
+		"Also read the option groups with this suffix, like mysqld's --defaults-group-suffix [optional]")
+	cli.flagset.BoolVarP(&cli.versionGroupsFlag, "version-groups", "", false,
+		"Also read sections for a patch version or a range of versions, e.g. [mysqld-8.0.30] or "+
+			"[mysqld >=8.0.30 <8.4], which mysqld itself ignores [optional]")
+	cli.flagset.StringVarP(&cli.persistedConfigFlag, "persisted-config", "", "",
+		"Path to the server's mysqld-auto.cnf with the variables saved by SET PERSIST, "+
+			"or 'datadir' to find it in the server's data directory [optional]")
+	cli.flagset.BoolVarP(&cli.reportSessionFlag, "report-session-divergence", "", false,
+		"Also report options whose session value differs from the global value [optional]")
+	cli.flagset.StringVarP(&cli.formatFlag, "format", "", formatText,
+		"The output format, either 'text' or 'json' [optional]")
+	cli.flagset.StringVarP(&cli.orderByFlag, "order-by", "", orderByName,
+		"The order to compare and apply options in, either 'name' or 'source' "+
+			"(the file and line they were set in) [optional]")
+	cli.flagset.StringVarP(&cli.emitSQLFlag, "emit-sql", "", "",
+		"Instead of applying the changes, write the SET statements to the given "+
+			"file, or to stdout with '-' [optional]")
+	cli.flagset.StringVarP(&cli.rollbackFileFlag, "rollback-file", "", "",
+		"Where --apply-changes writes the SQL script that restores the previous values. "+
+			"Defaults to rollback-<server>-<time>.sql in the current directory [optional]")
+	cli.flagset.StringVarP(&cli.applyModeFlag, "apply-mode", "", string(applyModeGlobal),
+		"How to apply the changes: 'global' (SET GLOBAL), 'persist' (SET PERSIST) or "+
+			"'persist-only' (SET PERSIST_ONLY, for the next restart). "+
+			"persist and persist-only require MySQL 8.0 [optional]")
+	cli.flagset.StringSliceVarP(&cli.skipVariablesFlag, "skip-variables", "", nil,
+		"With "+commandDiffServers+", a comma-separated list of variables to skip, in addition to "+
+			"the per-host variables such as server_id and server_uuid [optional]")
 	cli.flagset.BoolVarP(&cli.helpFlag, "help", "h", false, "Print this help message and exit")
 	cli.flagset.Usage = func() {
 		_, _ = fmt.Fprint(os.Stderr, cli.getHelpMessage())
@@ -56,31 +122,192 @@ func (c *InputContext) parseArgs(args []string) (
 	if c.helpFlag {
 		return nil, errHelpFlagIsSet
 	}
-	if len(c.positionals) != 2 {
+	if len(c.positionals) > 0 && c.positionals[0] == commandRollback {
+		return c.parseRollbackArgs()
+	}
+	if len(c.positionals) > 0 && c.positionals[0] == commandSnapshot {
+		return c.parseSnapshotArgs()
+	}
+	if len(c.positionals) > 0 && c.positionals[0] == commandDiffServers {
+		return c.parseDiffServersArgs()
+	}
+	if len(c.skipVariablesFlag) > 0 {
+		return nil, fmt.Errorf("--skip-variables can only be used with %s", commandDiffServers)
+	}
+	if len(c.positionals) != 1 && len(c.positionals) != 2 {
 		return nil, fmt.Errorf("invalid number of positional arguments")
 	}
+	// With a single positional argument, the option files are found the
+	// same way mysqld finds them.
+	configPath := ""
+	serverAndPort := c.positionals[0]
+	if len(c.positionals) == 2 {
+		configPath = c.positionals[0]
+		serverAndPort = c.positionals[1]
+	}
+	if configPath != "" && (c.defaultsFileFlag != "" || c.defaultsExtraFileFlag != "") {
+		return nil, fmt.Errorf("--defaults-file and --defaults-extra-file cannot be used with <path_to_my.cnf>")
+	}
+	if c.defaultsFileFlag != "" && c.defaultsExtraFileFlag != "" {
+		return nil, fmt.Errorf("--defaults-extra-file cannot be used with --defaults-file")
+	}
+	if c.formatFlag != formatText && c.formatFlag != formatJSON {
+		return nil, fmt.Errorf("invalid --format: %s", c.formatFlag)
+	}
+	if c.orderByFlag != orderByName && c.orderByFlag != orderBySource {
+		return nil, fmt.Errorf("invalid --order-by: %s", c.orderByFlag)
+	}
+	mode, err := parseApplyMode(c.applyModeFlag)
+	if err != nil {
+		return nil, err
+	}
+	if c.emitSQLFlag != "" && c.executeFlag {
+		return nil, fmt.Errorf("--emit-sql cannot be used with --apply-changes")
+	}
+	if c.emitSQLFlag == emitSQLToStdout && c.formatFlag == formatJSON {
+		return nil, fmt.Errorf("--emit-sql needs a file name when used with --format json")
+	}
 	if c.executeFlag && len(c.optionsToWatchFlag) == 0 {
 		return nil, fmt.Errorf("--watch-options required when running --apply-changes")
 	}
+	// A snapshot file can be given in place of <server:port>
+	snapshotFile := ""
+	if isSnapshotFile(serverAndPort) {
+		snapshotFile = serverAndPort
+		if c.executeFlag || c.reportSessionFlag {
+			return nil, fmt.Errorf("--apply-changes and --report-session-divergence need a server, not a snapshot")
+		}
+	}
 	// Handle the --watch-options flag
 	optionsToWatch := make(map[string]any)
 	for _, option := range c.optionsToWatchFlag {
 		optionsToWatch[option] = true
 	}
 	return &RunContext{
-		configPath:        c.positionals[0],
-		serverAndPort:     c.positionals[1],
+		configPath:        configPath,
+		defaultsFile:      c.defaultsFileFlag,
+		defaultsExtraFile: c.defaultsExtraFileFlag,
+		serverAndPort:     serverAndPort,
+		snapshotFile:      snapshotFile,
+		optionGroups: OptionGroups{
+			Patterns:      c.optionGroupsFlag,
+			Suffix:        c.groupSuffixFlag,
+			VersionRanges: c.versionGroupsFlag,
+		},
+		persistedConfig:   c.persistedConfigFlag,
+		reportSession:     c.reportSessionFlag,
+		outputFormat:      c.formatFlag,
+		orderBy:           c.orderByFlag,
+		emitSQL:           c.emitSQLFlag,
+		rollbackFile:      c.rollbackFileFlag,
+		applyMode:         mode,
 		optionKeysToWatch: optionsToWatch,
 		applyTheChanges:   c.executeFlag,
 	}, nil
 }
 
+// Validates the arguments of the rollback command, `rollback <file>
+// <server:port>`.
+func (c *InputContext) parseRollbackArgs() (*RunContext, error) {
+	if len(c.positionals) != 3 {
+		return nil, fmt.Errorf("invalid number of positional arguments for %s", commandRollback)
+	}
+	if err := c.rejectIgnoredFlags(rollbackIgnoredFlags, commandRollback); err != nil {
+		return nil, err
+	}
+	return &RunContext{
+		command:       commandRollback,
+		rollbackFile:  c.positionals[1],
+		serverAndPort: c.positionals[2],
+	}, nil
+}
+
+// The flags that have no effect on rollback, which only replays a rollback
+// script, and on snapshot, which saves every global variable as is.
+var rollbackIgnoredFlags = []string{
+	"watch-options", "apply-changes", "defaults-file", "defaults-extra-file", "option-groups",
+	"group-suffix", "version-groups", "persisted-config", "report-session-divergence", "format",
+	"order-by", "emit-sql", "rollback-file", "apply-mode", "skip-variables",
+}
+
+var snapshotIgnoredFlags = rollbackIgnoredFlags
+
+// Validates the arguments of the snapshot command, `snapshot <server:port>
+// <snapshot.json>`.
+func (c *InputContext) parseSnapshotArgs() (*RunContext, error) {
+	if len(c.positionals) != 3 {
+		return nil, fmt.Errorf("invalid number of positional arguments for %s", commandSnapshot)
+	}
+	if !isSnapshotFile(c.positionals[2]) {
+		return nil, fmt.Errorf("the snapshot file name must end with %s", snapshotFileExtension)
+	}
+	if err := c.rejectIgnoredFlags(snapshotIgnoredFlags, commandSnapshot); err != nil {
+		return nil, err
+	}
+	return &RunContext{
+		command:       commandSnapshot,
+		serverAndPort: c.positionals[1],
+		snapshotFile:  c.positionals[2],
+	}, nil
+}
+
+// The flags that have no effect on diff-servers, which compares servers
+// without option files and without changing them.
+var diffServersIgnoredFlags = []string{
+	"apply-changes", "emit-sql", "rollback-file", "apply-mode", "order-by",
+	"persisted-config", "report-session-divergence", "defaults-file", "defaults-extra-file",
+	"option-groups", "group-suffix", "version-groups",
+}
+
+// Validates the arguments of the diff-servers command, `diff-servers
+// <server:port> <server:port>`. Either server may be a snapshot file.
+func (c *InputContext) parseDiffServersArgs() (*RunContext, error) {
+	if len(c.positionals) != 3 {
+		return nil, fmt.Errorf("invalid number of positional arguments for %s", commandDiffServers)
+	}
+	if err := c.rejectIgnoredFlags(diffServersIgnoredFlags, commandDiffServers); err != nil {
+		return nil, err
+	}
+	if c.formatFlag != formatText && c.formatFlag != formatJSON {
+		return nil, fmt.Errorf("invalid --format: %s", c.formatFlag)
+	}
+	optionsToWatch := make(map[string]any)
+	for _, option := range c.optionsToWatchFlag {
+		optionsToWatch[option] = true
+	}
+	return &RunContext{
+		command:            commandDiffServers,
+		serverAndPort:      c.positionals[1],
+		otherServerAndPort: c.positionals[2],
+		variablesToSkip:    c.skipVariablesFlag,
+		outputFormat:       c.formatFlag,
+		optionKeysToWatch:  optionsToWatch,
+	}, nil
+}
+
+// Returns an error when one of the given flags, which have no effect on the
+// command, is set, rather than silently ignoring it.
+func (c *InputContext) rejectIgnoredFlags(names []string, command string) error {
+	for _, name := range names {
+		if c.flagset.Changed(name) {
+			return fmt.Errorf("--%s cannot be used with %s", name, command)
+		}
+	}
+	return nil
+}
+
 // Returns the help message to display to the user.
 func (c *InputContext) getHelpMessage() string {
 	var message strings.Builder
 
-	_, _ = fmt.Fprint(&message, "Usage: ", getBinaryName(), " <path_to_my.cnf> <server:port> "+
+	_, _ = fmt.Fprint(&message, "Usage: ", getBinaryName(), " [<path_to_my.cnf>] <server:port> "+
 		"[--watch-options option1,option2,option3 [--apply-changes]]")
+	_, _ = fmt.Fprint(&message, "\n       ", getBinaryName(), " ", commandRollback,
+		" <rollback_file.sql> <server:port>")
+	_, _ = fmt.Fprint(&message, "\n       ", getBinaryName(), " ", commandSnapshot,
+		" <server:port> <snapshot.json>")
+	_, _ = fmt.Fprint(&message, "\n       ", getBinaryName(), " ", commandDiffServers,
+		" <server:port> <server:port>")
 	_, _ = fmt.Fprint(&message, "\n\n")
 	_, _ = fmt.Fprint(&message,
 		"This utility checks the MySQL configuration on disk against the server variable "+
@@ -90,8 +317,25 @@ func (c *InputContext) getHelpMessage() string {
 			"server provided. The program can optionally *apply* changes found onto the "+
 			"running MySQL server."+
 			"\n\n"+
+			"If <path_to_my.cnf> is omitted, the option files are read in the same order "+
+			"as mysqld reads them: /etc/my.cnf, /etc/mysql/my.cnf, SYSCONFDIR/my.cnf, "+
+			"$MYSQL_HOME/my.cnf, the --defaults-extra-file and ~/.my.cnf."+
+			"\n\n"+
+			"Every --apply-changes run writes a rollback script with the previous values of "+
+			"the variables it changed, which the "+commandRollback+" command applies again."+
+			"\n\n"+
+			"The "+commandSnapshot+" command saves the version and global variables of a server to a "+
+			"JSON file, which can be given in place of <server:port> to compare without a connection."+
+			"\n\n"+
+			"The "+commandDiffServers+" command compares the global variables of two servers with each "+
+			"other, skipping per-host variables such as server_id and any given with --skip-variables."+
+			"\n\n"+
 			"Set environment variable $MYSQL_USER and $MYSQL_PASSWORD to specify connection "+
-			"information.")
+			"information."+
+			"\n\n"+
+			"Exit codes: 0 when in sync or every difference was applied, 1 on errors, "+
+			"2 when differences were found and not applied, and 3 when applying a change failed, "+
+			"was skipped or did not take effect.")
 	_, _ = fmt.Fprint(&message, "\n\n")
 	_, _ = fmt.Fprint(&message, c.flagset.FlagUsages())
 
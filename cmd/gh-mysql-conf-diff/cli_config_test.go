@@ -51,3 +51,190 @@ func TestExecuteRequiresWatchedOptions(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "--watch-options required")
 }
+
+func TestConfigPathIsOptional(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"localhost:1000", "--defaults-extra-file=extra.cnf"})
+	require.NoError(t, err)
+	require.Equal(t, "", context.configPath)
+	require.Equal(t, "extra.cnf", context.defaultsExtraFile)
+	require.Equal(t, "localhost:1000", context.serverAndPort)
+}
+
+func TestDefaultsFileConflicts(t *testing.T) {
+	_, err := newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--defaults-file=other.cnf"})
+	require.Error(t, err)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"localhost:1000", "--defaults-file=my.cnf", "--defaults-extra-file=extra.cnf"})
+	require.Error(t, err)
+}
+
+func TestOptionGroupFlags(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000"})
+	require.NoError(t, err)
+	// The groups are chosen by the flavor of the server once connected
+	require.Empty(t, context.optionGroups.Patterns)
+
+	context, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--option-groups=mysqld,mariadb-X.Y", "--group-suffix=_replica1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"mysqld", "mariadb-X.Y"}, context.optionGroups.Patterns)
+	require.Equal(t, "_replica1", context.optionGroups.Suffix)
+}
+
+func TestFormatFlag(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000"})
+	require.NoError(t, err)
+	require.Equal(t, "text", context.outputFormat)
+
+	context, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--format=json"})
+	require.NoError(t, err)
+	require.Equal(t, "json", context.outputFormat)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--format=yaml"})
+	require.Error(t, err)
+}
+
+func TestEmitSQLFlag(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--emit-sql", "-"})
+	require.NoError(t, err)
+	require.Equal(t, emitSQLToStdout, context.emitSQL)
+
+	context, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--emit-sql=changes.sql"})
+	require.NoError(t, err)
+	require.Equal(t, "changes.sql", context.emitSQL)
+
+	// The file name is the value of the flag, not a positional argument
+	context, err = newInputContext().parseArgs(
+		[]string{"localhost:1000", "--emit-sql", "changes.sql"})
+	require.NoError(t, err)
+	require.Equal(t, "changes.sql", context.emitSQL)
+	require.Equal(t, "localhost:1000", context.serverAndPort)
+	require.Empty(t, context.configPath)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--emit-sql"})
+	require.Error(t, err)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--emit-sql", "-", "--watch-options=a", "--apply-changes"})
+	require.Error(t, err)
+}
+
+func TestApplyModeFlag(t *testing.T) {
+	context, err := newInputContext().parseArgs([]string{"my.cnf", "localhost:1000"})
+	require.NoError(t, err)
+	require.Equal(t, applyModeGlobal, context.applyMode)
+
+	context, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--apply-mode", "persist"})
+	require.NoError(t, err)
+	require.Equal(t, applyModePersist, context.applyMode)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "localhost:1000", "--apply-mode", "session"})
+	require.Error(t, err)
+}
+
+func TestRollbackCommand(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"rollback", "rollback.sql", "localhost:1000"})
+	require.NoError(t, err)
+	require.Equal(t, commandRollback, context.command)
+	require.Equal(t, "rollback.sql", context.rollbackFile)
+	require.Equal(t, "localhost:1000", context.serverAndPort)
+
+	_, err = newInputContext().parseArgs([]string{"rollback", "rollback.sql"})
+	require.Error(t, err)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"rollback", "rollback.sql", "localhost:1000", "--watch-options=a", "--apply-changes"})
+	require.Error(t, err)
+
+	// Flags that would have no effect are rejected rather than ignored
+	for _, flag := range []string{
+		"--apply-mode=persist", "--format=json", "--watch-options=a", "--option-groups=mysqld",
+		"--emit-sql=-", "--rollback-file=other.sql", "--skip-variables=read_only",
+	} {
+		_, err = newInputContext().parseArgs([]string{"rollback", "rollback.sql", "localhost:1000", flag})
+		require.ErrorContains(t, err, "cannot be used with rollback", flag)
+	}
+}
+
+func TestSnapshotCommand(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"snapshot", "localhost:1000", "snapshot.json"})
+	require.NoError(t, err)
+	require.Equal(t, commandSnapshot, context.command)
+	require.Equal(t, "localhost:1000", context.serverAndPort)
+	require.Equal(t, "snapshot.json", context.snapshotFile)
+
+	_, err = newInputContext().parseArgs([]string{"snapshot", "localhost:1000", "snapshot.txt"})
+	require.Error(t, err)
+
+	_, err = newInputContext().parseArgs([]string{"snapshot", "localhost:1000"})
+	require.Error(t, err)
+
+	// Flags that would have no effect are rejected rather than ignored
+	for _, flag := range []string{
+		"--apply-mode=persist", "--format=json", "--watch-options=a", "--option-groups=mysqld",
+		"--apply-changes", "--emit-sql=-", "--skip-variables=read_only",
+	} {
+		_, err = newInputContext().parseArgs([]string{"snapshot", "localhost:1000", "snapshot.json", flag})
+		require.ErrorContains(t, err, "cannot be used with snapshot", flag)
+	}
+}
+
+func TestSnapshotInPlaceOfServer(t *testing.T) {
+	context, err := newInputContext().parseArgs([]string{"my.cnf", "snapshot.json"})
+	require.NoError(t, err)
+	require.Equal(t, "snapshot.json", context.snapshotFile)
+
+	context, err = newInputContext().parseArgs([]string{"my.cnf", "localhost:1000"})
+	require.NoError(t, err)
+	require.Empty(t, context.snapshotFile)
+
+	// A snapshot can't be changed
+	_, err = newInputContext().parseArgs(
+		[]string{"my.cnf", "snapshot.json", "--watch-options=a", "--apply-changes"})
+	require.Error(t, err)
+}
+
+func TestDiffServersCommand(t *testing.T) {
+	context, err := newInputContext().parseArgs(
+		[]string{"diff-servers", "db1:3306", "db2.json", "--skip-variables=sync_binlog,read_only"})
+	require.NoError(t, err)
+	require.Equal(t, commandDiffServers, context.command)
+	require.Equal(t, "db1:3306", context.serverAndPort)
+	require.Equal(t, "db2.json", context.otherServerAndPort)
+	require.Equal(t, []string{"sync_binlog", "read_only"}, context.variablesToSkip)
+
+	_, err = newInputContext().parseArgs([]string{"diff-servers", "db1:3306"})
+	require.Error(t, err)
+
+	_, err = newInputContext().parseArgs(
+		[]string{"diff-servers", "db1:3306", "db2:3306", "--watch-options=a", "--apply-changes"})
+	require.Error(t, err)
+
+	// Flags that would have no effect are rejected rather than ignored
+	for _, flag := range []string{
+		"--order-by=name", "--apply-mode=persist", "--persisted-config=datadir",
+		"--report-session-divergence", "--defaults-file=my.cnf", "--defaults-extra-file=extra.cnf",
+		"--option-groups=mysqld", "--group-suffix=_replica1", "--version-groups",
+	} {
+		_, err = newInputContext().parseArgs([]string{"diff-servers", "db1:3306", "db2:3306", flag})
+		require.ErrorContains(t, err, "cannot be used with diff-servers", flag)
+	}
+
+	// Skipping variables only applies to diff-servers
+	_, err = newInputContext().parseArgs([]string{"my.cnf", "db1:3306", "--skip-variables=read_only"})
+	require.Error(t, err)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DiffStatus is the outcome of comparing a single option.
+type DiffStatus string
+
+const (
+	// StatusEqual means the config and server values are the same.
+	StatusEqual DiffStatus = "equal"
+	// StatusDifferent means the config and server values differ.
+	StatusDifferent DiffStatus = "different"
+	// StatusMissing means the option was not found in server variables.
+	StatusMissing DiffStatus = "missing"
+	// StatusPluginNotLoaded means a loose- option was not found in server
+	// variables, most likely because its plugin is not loaded.
+	StatusPluginNotLoaded DiffStatus = "plugin_not_loaded"
+	// StatusApplied means the values differed and the config value was
+	// applied to the server.
+	StatusApplied DiffStatus = "applied"
+	// StatusStaged means the config value was saved with SET PERSIST_ONLY
+	// and takes effect when the server restarts.
+	StatusStaged DiffStatus = "staged"
+	// StatusAdjusted means the config value was applied, but the server
+	// rounded or clamped it to a different value.
+	StatusAdjusted DiffStatus = "applied_with_adjustment"
+	// StatusNotApplied means applying the config value did not fail, but
+	// the server still reports the previous value.
+	StatusNotApplied DiffStatus = "not_applied"
+	// StatusFailed means the values differed and applying the config
+	// value to the server failed.
+	StatusFailed DiffStatus = "failed"
+	// StatusReadOnly means the values differed, but the config value could
+	// not be applied because the variable is read-only at runtime.
+	StatusReadOnly DiffStatus = "read_only"
+	// StatusSkipped means the values differed, but the config value was not
+	// applied because it failed validation.
+	StatusSkipped DiffStatus = "skipped"
+)
+
+// OptionResult is the result of comparing a single option.
+type OptionResult struct {
+	Key         string     `json:"key"`
+	ConfigValue string     `json:"config_value"`
+	ServerValue string     `json:"server_value"`
+	Status      DiffStatus `json:"status"`
+	ApplyError  string     `json:"apply_error,omitempty"`
+	// ApplyErrorNumber is the MySQL error number applying the value failed
+	// with, e.g. 1238 for a read-only variable.
+	ApplyErrorNumber uint16 `json:"apply_error_number,omitempty"`
+	// ApplyExplanation explains why applying the value failed.
+	ApplyExplanation string `json:"apply_explanation,omitempty"`
+	// ApplyWarnings are the warnings MySQL raised when applying the value.
+	ApplyWarnings []string `json:"apply_warnings,omitempty"`
+	// AppliedValue is the value the server reports after applying the
+	// config value, when it could be read back.
+	AppliedValue *string `json:"applied_value,omitempty"`
+	// PersistedValue is the value saved with SET PERSIST before applying
+	// the config value with --apply-mode persist or persist-only, if any.
+	PersistedValue *string         `json:"persisted_value,omitempty"`
+	ConfigSource   *OptionSource   `json:"config_source,omitempty"`
+	ServerSource   *VariableSource `json:"server_source,omitempty"`
+	// Notes are lint findings on the config value, e.g. the variable being
+	// deprecated or the value being out of range, and notes on how it was
+	// compared, e.g. the server rounding it.
+	Notes []string `json:"notes,omitempty"`
+	// Metadata is the metadata of the variable, if known.
+	Metadata *VariableMetadata `json:"-"`
+	// SessionValue is set when the session value of the variable differs
+	// from the global value, with --report-session-divergence.
+	SessionValue *string `json:"session_value,omitempty"`
+}
+
+// Returns the values that change the variable from the server value to the
+// config value, and back (see changeValues).
+func (r *OptionResult) changeValues() (apply, rollback string) {
+	return changeValues(r.Metadata, r.ConfigValue, r.ServerValue)
+}
+
+// DiffReport is the result of a whole run.
+type DiffReport struct {
+	Server          string   `json:"server"`
+	Version         string   `json:"version"`
+	Flavor          Flavor   `json:"flavor,omitempty"`
+	ConfigFiles     []string `json:"config_files"`
+	PersistedConfig string   `json:"persisted_config,omitempty"`
+	// Snapshot is the snapshot file the server variables were read from,
+	// instead of the server itself.
+	Snapshot string `json:"snapshot,omitempty"`
+	// ApplyMode is how the changes were applied or would be applied, with
+	// --apply-changes or --emit-sql.
+	ApplyMode applyMode `json:"apply_mode,omitempty"`
+	// Notes are about the report as a whole, e.g. the metadata of the
+	// variables being unavailable for the server.
+	Notes   []string        `json:"notes,omitempty"`
+	Options []*OptionResult `json:"options"`
+}
+
+// Summary counts the compared options by status.
+func (r *DiffReport) Summary() map[DiffStatus]int {
+	summary := make(map[DiffStatus]int)
+	for _, result := range r.Options {
+		summary[result.Status]++
+	}
+	return summary
+}
+
+// ExitCode returns the exit code of the program for the report. Failed
+// applies, and applies that did not take effect, take precedence over
+// unapplied differences.
+func (r *DiffReport) ExitCode() int {
+	summary := r.Summary()
+	if summary[StatusFailed] > 0 || summary[StatusReadOnly] > 0 ||
+		summary[StatusSkipped] > 0 || summary[StatusNotApplied] > 0 {
+		return exitApplyFailed
+	}
+	if summary[StatusDifferent] > 0 {
+		return exitDrift
+	}
+	return exitInSync
+}
+
+// MarshalJSON adds the summary to the JSON form of the report.
+func (r *DiffReport) MarshalJSON() ([]byte, error) {
+	type report DiffReport
+	return json.Marshal(struct {
+		*report
+		Summary map[DiffStatus]int `json:"summary"`
+	}{(*report)(r), r.Summary()})
+}
+
+// The output formats supported by --format.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// Writes the report in the given format.
+func writeReport(report *DiffReport, format string, stdout, stderr io.Writer) error {
+	switch format {
+	case formatJSON:
+		return writeJSONReport(report, stdout)
+	case formatText:
+		writeTextReport(report, stdout, stderr)
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// Writes the report as a single JSON document.
+func writeJSONReport(report *DiffReport, stdout io.Writer) error {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Writes the report as human readable text. Differences are printed to
+// stdout, while the files read and warnings are printed to stderr.
+func writeTextReport(report *DiffReport, stdout, stderr io.Writer) {
+	if len(report.ConfigFiles) > 0 {
+		_, _ = fmt.Fprintf(stderr, "Read option files in order:\n")
+		for _, file := range report.ConfigFiles {
+			_, _ = fmt.Fprintf(stderr, "  %s\n", file)
+		}
+	}
+	if report.PersistedConfig != "" {
+		_, _ = fmt.Fprintf(stderr, "Read persisted config:\n  %s\n", report.PersistedConfig)
+	}
+	for _, note := range report.Notes {
+		_, _ = fmt.Fprintf(stderr, "Note: %s\n", note)
+	}
+	for _, result := range report.Options {
+		if result.SessionValue == nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(stdout, "Session value differs from global for: %s\n", result.Key)
+		_, _ = fmt.Fprintf(stdout, "  global:    %s\n", result.ServerValue)
+		_, _ = fmt.Fprintf(stdout, "  session:   %s\n", *result.SessionValue)
+	}
+	for _, result := range report.Options {
+		writeTextResult(result, stdout, stderr)
+	}
+}
+
+// Writes the result for a single option as human readable text.
+func writeTextResult(result *OptionResult, stdout, stderr io.Writer) {
+	source := result.ConfigSource
+	for _, note := range result.Notes {
+		_, _ = fmt.Fprintf(stderr, "Note: option '%s'%s: %s\n", result.Key, describeSource(source), note)
+	}
+	switch result.Status {
+	case StatusEqual:
+		return // Nothing to report
+	case StatusPluginNotLoaded:
+		_, _ = fmt.Fprintf(stderr,
+			"Plugin not loaded: loose option '%s'%s in configuration "+
+				"file was not found in server variables\n", result.Key, describeSource(source))
+		return
+	case StatusMissing:
+		_, _ = fmt.Fprintf(stderr,
+			"Warning: option '%s'%s in configuration file was "+
+				"not found in server variables\n", result.Key, describeSource(source))
+		return
+	}
+	// Report on any differences to console user, showing only the part of
+	// the values that differs
+	configValue, serverValue := result.changeValues()
+	_, _ = fmt.Fprintf(stdout, "Difference found for: %s\n", result.Key)
+	// Values saved with SET PERSIST don't come from my.cnf
+	if source != nil && source.Persisted {
+		_, _ = fmt.Fprintf(stdout, "  persisted: %s%s\n", configValue, describeSource(source))
+	} else {
+		_, _ = fmt.Fprintf(stdout, "  my.cnf:    %s%s\n", configValue, describeSource(source))
+	}
+	if source != nil {
+		for _, overridden := range source.Overrides {
+			_, _ = fmt.Fprintf(stdout, "  overrode:  %s (%s)\n", overridden.Value, overridden)
+		}
+	}
+	if result.ServerSource != nil {
+		_, _ = fmt.Fprintf(stdout, "  mysqld:    %s (%s)\n", serverValue, result.ServerSource)
+	} else {
+		_, _ = fmt.Fprintf(stdout, "  mysqld:    %s\n", serverValue)
+	}
+	switch result.Status {
+	case StatusApplied, StatusAdjusted:
+		_, _ = fmt.Fprintf(stdout, "Set variable:\n  %s = %s\n", result.Key, configValue)
+		for _, warning := range result.ApplyWarnings {
+			_, _ = fmt.Fprintf(stderr, "Warning: SET variable %s: %s\n", result.Key, warning)
+		}
+		if result.AppliedValue == nil {
+			return // Not verified
+		}
+		if result.Status == StatusAdjusted {
+			_, _ = fmt.Fprintf(stdout, "  applied with adjustment (requested %s, got %s)\n",
+				result.ConfigValue, *result.AppliedValue)
+		} else {
+			_, _ = fmt.Fprintf(stdout, "  applied\n")
+		}
+	case StatusNotApplied:
+		// The SET succeeded, but the server kept the previous value
+		_, _ = fmt.Fprintf(stdout, "Not applied: %s (requested %s, got %s)\n",
+			result.Key, result.ConfigValue, *result.AppliedValue)
+		for _, warning := range result.ApplyWarnings {
+			_, _ = fmt.Fprintf(stderr, "Warning: SET variable %s: %s\n", result.Key, warning)
+		}
+	case StatusStaged:
+		_, _ = fmt.Fprintf(stdout, "Persisted variable for the next restart:\n  %s = %s\n",
+			result.Key, configValue)
+		for _, warning := range result.ApplyWarnings {
+			_, _ = fmt.Fprintf(stderr, "Warning: SET variable %s: %s\n", result.Key, warning)
+		}
+	case StatusFailed:
+		_, _ = fmt.Fprintf(stderr, "Warning: Failed to SET variable: %s\n", result.ApplyError)
+		writeApplyExplanation(result, stderr)
+	case StatusReadOnly:
+		_, _ = fmt.Fprintf(stderr, "Warning: Cannot SET read-only variable: %s\n", result.ApplyError)
+		writeApplyExplanation(result, stderr)
+	case StatusSkipped:
+		_, _ = fmt.Fprintf(stderr, "Warning: Skipped SET variable: %s\n", result.ApplyError)
+		writeApplyExplanation(result, stderr)
+	}
+}
+
+// Writes why applying the config value of an option failed, if known.
+func writeApplyExplanation(result *OptionResult, stderr io.Writer) {
+	if result.ApplyExplanation == "" {
+		return
+	}
+	if result.ApplyErrorNumber != 0 {
+		_, _ = fmt.Fprintf(stderr, "  MySQL error %d: %s\n", result.ApplyErrorNumber, result.ApplyExplanation)
+		return
+	}
+	_, _ = fmt.Fprintf(stderr, "  %s\n", result.ApplyExplanation)
+}
+
+// Returns the location of an option for display after its value, e.g.
+// ` (my.cnf:12 [mysqld])`, or an empty string if the location is unknown.
+func describeSource(source *OptionSource) string {
+	if source == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", source)
+}
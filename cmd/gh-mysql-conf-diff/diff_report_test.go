@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSONReport(t *testing.T) {
+	report := &DiffReport{
+		Server:      "localhost:3306",
+		Version:     MySQLVersion{Major: 8, Minor: 0, Patch: 28}.String(),
+		ConfigFiles: []string{"/etc/my.cnf"},
+		Options: []*OptionResult{
+			{
+				Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusFailed,
+				ApplyError: "access denied",
+				ConfigSource: &OptionSource{
+					Name: "connect_timeout", Value: "60", File: "/etc/my.cnf", Line: 2, Section: "mysqld",
+				},
+			},
+			{Key: "MAX_CONNECTIONS", ConfigValue: "151", ServerValue: "151", Status: StatusEqual},
+		},
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	err := writeReport(report, formatJSON, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Empty(t, stderr.String())
+
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &document))
+	assert.Equal(t, "localhost:3306", document["server"])
+	assert.Equal(t, "8.0.28", document["version"])
+	options := document["options"].([]any)
+	require.Len(t, options, 2)
+	first := options[0].(map[string]any)
+	assert.Equal(t, "CONNECT_TIMEOUT", first["key"])
+	assert.Equal(t, "failed", first["status"])
+	assert.Equal(t, "access denied", first["apply_error"])
+	assert.Equal(t, "/etc/my.cnf", first["config_source"].(map[string]any)["file"])
+	second := options[1].(map[string]any)
+	assert.Equal(t, "equal", second["status"])
+	summary := document["summary"].(map[string]any)
+	assert.Equal(t, map[string]any{"failed": 1.0, "equal": 1.0}, summary)
+}
+
+func TestReportExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []DiffStatus
+		expected int
+	}{
+		{"when nothing was compared", nil, exitInSync},
+		{"when in sync", []DiffStatus{StatusEqual, StatusMissing}, exitInSync},
+		{"when all changes were applied", []DiffStatus{StatusEqual, StatusApplied}, exitInSync},
+		{"when drift was detected", []DiffStatus{StatusEqual, StatusDifferent}, exitDrift},
+		{"when an apply failed", []DiffStatus{StatusApplied, StatusDifferent, StatusFailed}, exitApplyFailed},
+		{"when an apply did not take effect", []DiffStatus{StatusAdjusted, StatusNotApplied}, exitApplyFailed},
+		{"when a variable was read-only", []DiffStatus{StatusApplied, StatusReadOnly}, exitApplyFailed},
+		{"when an apply was skipped", []DiffStatus{StatusEqual, StatusSkipped}, exitApplyFailed},
+		{"when an apply was adjusted", []DiffStatus{StatusEqual, StatusAdjusted}, exitInSync},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &DiffReport{}
+			for _, status := range tt.statuses {
+				report.Options = append(report.Options, &OptionResult{Status: status})
+			}
+			require.Equal(t, tt.expected, report.ExitCode())
+		})
+	}
+}
+
+func TestWriteTextReportFailedApply(t *testing.T) {
+	report := &DiffReport{
+		Options: []*OptionResult{{
+			Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusFailed,
+			ApplyError: "access denied",
+		}},
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	writeTextReport(report, &stdout, &stderr)
+
+	assert.Contains(t, stdout.String(), "Difference found for: CONNECT_TIMEOUT")
+	assert.NotContains(t, stdout.String(), "Set variable")
+	assert.Equal(t, "Warning: Failed to SET variable: access denied\n", stderr.String())
+}
+
+func TestWriteTextReportReadOnlyApply(t *testing.T) {
+	report := &DiffReport{
+		Options: []*OptionResult{{
+			Key: "DATADIR", ConfigValue: "/data/mysql", ServerValue: "/var/lib/mysql", Status: StatusReadOnly,
+			ApplyError:       "Error 1238 (HY000): Variable 'datadir' is a read only variable",
+			ApplyErrorNumber: 1238,
+			ApplyExplanation: applyErrorExplanations[1238],
+		}},
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	writeTextReport(report, &stdout, &stderr)
+
+	assert.NotContains(t, stdout.String(), "Set variable")
+	assert.Equal(t, "Warning: Cannot SET read-only variable: "+
+		"Error 1238 (HY000): Variable 'datadir' is a read only variable\n"+
+		"  MySQL error 1238: the variable is read-only and can only be set at startup, e.g. in my.cnf\n",
+		stderr.String())
+}
+
+func TestWriteTextReportNotes(t *testing.T) {
+	report := &DiffReport{
+		Notes:   metadataNotes(MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}),
+		Options: []*OptionResult{},
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	writeTextReport(report, &stdout, &stderr)
+
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, "Note: MariaDB metadata is unavailable: only the variables shared with MySQL "+
+		"are compared by type, without checking ranges, defaults or deprecations\n", stderr.String())
+}
@@ -0,0 +1,75 @@
+package main
+
+import "strings"
+
+// A flag in a flag list variable such as optimizer_switch, e.g.
+// `index_merge=on`.
+type flag struct {
+	name  string
+	value string
+}
+
+// The flag value that resets a flag to its default. The default is not
+// known, so flags set to it are not compared. On its own, e.g.
+// `optimizer_switch=default`, it resets all the flags.
+const defaultFlagValue = "default"
+
+// Parses a comma-separated list of name=value flags, in order. Names and
+// values are matched case-insensitively by mysqld, so they are lower
+// cased. A bare default item resets the flags before it, so they are
+// dropped, and is not returned itself.
+func parseFlags(value string) []flag {
+	var flags []flag
+	for _, item := range strings.Split(value, ",") {
+		name, flagValue, hasValue := strings.Cut(item, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == defaultFlagValue && !hasValue {
+			flags = nil
+			continue
+		}
+		flags = append(flags, flag{name: name, value: strings.ToLower(strings.TrimSpace(flagValue))})
+	}
+	return flags
+}
+
+// Formats flags as a comma-separated list of name=value flags.
+func formatFlags(flags []flag) string {
+	items := make([]string, 0, len(flags))
+	for _, f := range flags {
+		items = append(items, f.name+"="+f.value)
+	}
+	return strings.Join(items, ",")
+}
+
+// Returns the flags that are set in the option value and differ on the
+// server, both as the option sets them and as the server has them. A
+// config usually sets a few flags, while the server reports all of them,
+// so the other flags are left out.
+func diffFlags(optionValue, serverValue string) (optionFlags, serverFlags []flag) {
+	server := make(map[string]string)
+	for _, f := range parseFlags(serverValue) {
+		server[f.name] = f.value
+	}
+	for _, f := range parseFlags(optionValue) {
+		serverFlagValue, ok := server[f.name]
+		if f.value == defaultFlagValue || (ok && serverFlagValue == f.value) {
+			continue
+		}
+		optionFlags = append(optionFlags, f)
+		// A flag the server doesn't have has no value to restore
+		if ok {
+			serverFlags = append(serverFlags, flag{name: f.name, value: serverFlagValue})
+		}
+	}
+	return optionFlags, serverFlags
+}
+
+// Compares flag lists, such as optimizer_switch, by the flags set in the
+// option value only.
+func isEqualFlags(optionValue, serverValue string) bool {
+	optionFlags, _ := diffFlags(optionValue, serverValue)
+	return len(optionFlags) == 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const serverOptimizerSwitch = "index_merge=on,index_merge_union=on,mrr=on,mrr_cost_based=on," +
+	"block_nested_loop=on,derived_merge=on,hash_join=on"
+
+func TestIsEqualFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		optionValue string
+		expected    bool
+	}{
+		{"when the set flags match", "mrr=on,hash_join=on", true},
+		{"when flags differ in case and spacing", " MRR = ON , Hash_Join=on", true},
+		{"when a set flag differs", "mrr=on,hash_join=off", false},
+		{"when a flag is set to default", "mrr_cost_based=default", true},
+		{"when all flags are reset to default", "default", true},
+		{"when flags are set before a reset to default", "mrr=off,DEFAULT", true},
+		{"when flags are set after a reset to default", "default,mrr=off", false},
+		{"when a flag is unknown to the server", "no_such_flag=on", false},
+		{"when no flags are set", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isEqualFlags(tt.optionValue, serverOptimizerSwitch))
+		})
+	}
+}
+
+func TestChangeValues(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+
+	// Only the flags that differ are changed, in the order of the config
+	apply, rollback := changeValues(catalog["OPTIMIZER_SWITCH"],
+		"hash_join=off,mrr=on,block_nested_loop=OFF", serverOptimizerSwitch)
+	require.Equal(t, "hash_join=off,block_nested_loop=off", apply)
+	require.Equal(t, "hash_join=on,block_nested_loop=on", rollback)
+
+	// A reset to default is not applied, only the flags set after it
+	apply, rollback = changeValues(catalog["OPTIMIZER_SWITCH"], "default", serverOptimizerSwitch)
+	require.Equal(t, "", apply)
+	require.Equal(t, "", rollback)
+	apply, rollback = changeValues(catalog["OPTIMIZER_SWITCH"], "hash_join=off,default,mrr=off",
+		serverOptimizerSwitch)
+	require.Equal(t, "mrr=off", apply)
+	require.Equal(t, "mrr=on", rollback)
+
+	// Other variables are changed to the whole value
+	apply, rollback = changeValues(catalog["SQL_MODE"], "STRICT_TRANS_TABLES", "")
+	require.Equal(t, "STRICT_TRANS_TABLES", apply)
+	require.Equal(t, "", rollback)
+}
@@ -10,9 +10,15 @@
 //	$ gh-mysql-conf-diff /etc/mysql/my.cnf localhost:3306
 //
 //	Difference found for: CONNECT_TIMEOUT
-//	  my.cnf:    60
+//	  my.cnf:    60 (/etc/mysql/conf.d/timeouts.cnf:12 [mysqld-8.0])
 //	  mysqld:    30
 //
+// If the my.cnf path is omitted, the utility reads the same option files
+// mysqld would, in the same order, honoring `--defaults-file` and
+// `--defaults-extra-file`:
+//
+//	$ gh-mysql-conf-diff localhost:3306
+//
 // By default the utility runs in read only (informational mode). To apply the
 // changes, use the `--apply-changes` flag. This is not enabled by default. If
 // you run `--apply-changes` you need to use `--watch-options“ as well:
@@ -20,9 +26,43 @@
 //	$ gh-mysql-conf-diff /etc/mysql/my.cnf localhost:3306 \
 //	   --watch-options connect_timeout,delay_key_write --apply-changes
 //
+// Changes are applied with SET GLOBAL by default. On MySQL 8.0, use
+// `--apply-mode persist` to also save them for the next restart, or
+// `--apply-mode persist-only` to only save them, e.g. for read-only
+// variables.
+//
+// Each run that applies changes writes a rollback script with the previous
+// values, in reverse order, to `--rollback-file` (by default
+// `rollback-<server>-<time>.sql`). The `rollback` command applies it again:
+//
+//	$ gh-mysql-conf-diff rollback rollback-localhost_3306-20240102T031200.sql localhost:3306
+//
+// The `snapshot` command saves the version, global variables and variable
+// sources of a server to a JSON file. The file can be given in place of
+// `server:port` to compare a config without connecting, e.g. in CI:
+//
+//	$ gh-mysql-conf-diff snapshot db1:3306 db1.json
+//	$ gh-mysql-conf-diff /etc/mysql/my.cnf db1.json
+//
+// The `diff-servers` command compares the global variables of two servers
+// (or snapshots) with each other, e.g. a replica with its primary. Variables
+// that identify the host, such as server_id, are skipped, along with any
+// given with `--skip-variables`:
+//
+//	$ gh-mysql-conf-diff diff-servers db1:3306 db2:3306
+//
 // The program needs to connect to MySQL with a user that has the correct
 // permissions. The username and password combo can be set using environment
 // variables `$MYSQL_USER` and `$MYSQL_PASSWORD`.
+//
+// The exit code of the program reflects the outcome of the diff:
+//
+//	0  in sync, or every difference found was applied
+//	1  error, e.g. invalid arguments, a connection failure or a config that
+//	   could not be parsed
+//	2  drift detected, i.e. differences were found and not applied
+//	3  applying one or more changes failed, was skipped or did not take
+//	   effect
 package main
 
 import (
@@ -30,7 +70,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
+)
+
+// Exit codes of the program, see the package documentation.
+const (
+	exitInSync      = 0
+	exitError       = 1
+	exitDrift       = 2
+	exitApplyFailed = 3
 )
 
 func main() {
@@ -44,33 +94,66 @@ func runWithReturnCode() int {
 	if err != nil {
 		if errors.Is(err, errHelpFlagIsSet) {
 			_, _ = fmt.Fprintf(os.Stderr, "%s", cli.getHelpMessage())
-			return 0
+			return exitInSync
 		}
 		_, _ = fmt.Fprintf(os.Stderr, "Failed to parse arguments: %v\n", err)
-		return 1
+		return exitError
+	}
+	switch context.command {
+	case commandRollback:
+		return runRollback(context)
+	case commandSnapshot:
+		return runSnapshot(context)
+	case commandDiffServers:
+		return runDiffServers(context)
 	}
 	// If --apply-changes, then fail if no --watch-options
 	if context.applyTheChanges && len(context.optionKeysToWatch) == 0 {
 		_, _ = fmt.Fprintf(os.Stderr, "Fatal: --watch-options is required when using --apply-changes\n")
-		return 1
+		return exitError
 	}
-	// Get the DB connection
-	db, err := getDB(context)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		return 1
+	// Get the DB connection, or the snapshot of the server to compare with
+	// instead
+	var db *dbConn
+	var source variableSource
+	server := context.serverAndPort
+	if context.snapshotFile != "" {
+		snapshot, err := readSnapshot(context.snapshotFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to read snapshot: %v\n", err)
+			return exitError
+		}
+		source = snapshot
+		server = snapshot.Server
+	} else {
+		db, err = getDB(context)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return exitError
+		}
+		defer db.close()
+		source = db
 	}
-	defer db.close()
 	// Get the two option maps, one from my.cnf, and one from the
 	// server variables for comparison.
-	confOptions, serverVariables, err := getOptionsFrom(context.configPath, db)
+	input, err := getOptionsFrom(context, source, os.Stderr)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		return 1
+		return exitError
+	}
+	// If --apply-mode, check that the server supports it before changing
+	// anything
+	if context.applyTheChanges || context.emitSQL != "" {
+		err = context.applyMode.checkSupported(input.version)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return exitError
+		}
 	}
 	// If --watch-options is set with any values, use it as a filter to limit
 	// the options to these values. If it is not set, then all options are
 	// used.
+	confOptions := input.confOptions
 	if len(context.optionKeysToWatch) > 0 {
 		// Limit the my.cnf options to those specified by --watch-options
 		confOptions = limitToWatchedOptions(confOptions, context.optionKeysToWatch)
@@ -79,20 +162,199 @@ func runWithReturnCode() int {
 		// If we don't limit to this, it will generate a lot of warnings,
 		// because the my.cnf file allows additional options (e.g. `USER` or
 		// `REPLICATE_SAME_SERVER_ID`) than the server variables.
-		confOptions = limitToWatchedOptions(confOptions, serverVariables)
+		confOptions = limitToWatchedOptions(confOptions, input.serverVariables)
+	}
+	// Compare the options maps. Changes are applied below, once the
+	// rollback script is saved.
+	results := mysqlConfDiff(
+		confOptions, input.serverVariables, input.confSources, input.serverSources,
+		input.catalog, context.orderBy)
+	report := &DiffReport{
+		Server:          server,
+		Snapshot:        context.snapshotFile,
+		Version:         input.version.String(),
+		Flavor:          input.version.Flavor,
+		ConfigFiles:     input.configFiles,
+		PersistedConfig: input.persistedConfig,
+		Notes:           metadataNotes(input.version),
+		Options:         results,
+	}
+	if context.applyTheChanges || context.emitSQL != "" {
+		report.ApplyMode = context.applyMode
+	}
+	// If --apply-changes, apply the changes, with a rollback script that
+	// restores the previous values
+	if context.applyTheChanges && hasRollbackChanges(report) {
+		now := time.Now()
+		rollbackFile := context.rollbackFile
+		if rollbackFile == "" {
+			rollbackFile = defaultRollbackFileName(context.serverAndPort, now)
+		}
+		err = applyWithRollback(db, report, now, rollbackFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Wrote rollback script: %s\n", rollbackFile)
+	}
+	// If --report-session-divergence, record the options whose session
+	// value differs from the global value being compared.
+	if context.reportSession {
+		sessionVariables, err := db.getSessionVariables()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to query MySQL for session variables: %v\n", err)
+			return exitError
+		}
+		addSessionValues(results, sessionVariables)
+	}
+	// Print the results to stdout and stderr in the requested format
+	// When the SQL script goes to stdout, the report moves to stderr so
+	// that the script can be piped or redirected on its own.
+	reportOut := io.Writer(os.Stdout)
+	if context.emitSQL == emitSQLToStdout {
+		reportOut = os.Stderr
+	}
+	err = writeReport(report, context.outputFormat, reportOut, os.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return exitError
+	}
+	// If --emit-sql, write the statements that would apply the changes
+	if context.emitSQL != "" {
+		err = emitSQLScript(report, context.emitSQL)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to write SQL script: %v\n", err)
+			return exitError
+		}
+	}
+	return report.ExitCode()
+}
+
+// Runs the rollback command, which replays a rollback script written by an
+// earlier --apply-changes run.
+func runRollback(context *RunContext) int {
+	db, err := getDB(context)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitError
+	}
+	defer db.close()
+	exitCode, err := rollback(db, context.rollbackFile, os.Stdout, os.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to roll back: %v\n", err)
+	}
+	return exitCode
+}
+
+// Runs the snapshot command, which saves the version, global variables and
+// variable sources of a server to a file, so that configs can be compared
+// with it later without a connection.
+func runSnapshot(context *RunContext) int {
+	db, err := getDB(context)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitError
+	}
+	defer db.close()
+	snapshot, err := takeSnapshot(db, context.serverAndPort, time.Now(), os.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitError
+	}
+	err = saveSnapshot(snapshot, context.snapshotFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to write snapshot: %v\n", err)
+		return exitError
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Wrote snapshot: %s\n", context.snapshotFile)
+	return exitInSync
+}
+
+// Runs the diff-servers command, which compares the global variables of two
+// servers, either of which may be a snapshot.
+func runDiffServers(context *RunContext) int {
+	var servers []*ServerVariables
+	for _, server := range []string{context.serverAndPort, context.otherServerAndPort} {
+		variables, err := getServerVariables(server)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return exitError
+		}
+		servers = append(servers, variables)
+	}
+	report := diffServers(servers[0], servers[1],
+		systemVariables.ForVersion(servers[0].Version),
+		variablesToSkip(context.variablesToSkip), normalizeKeys(context.optionKeysToWatch))
+	err := writeServerDiffReport(report, context.outputFormat, os.Stdout, os.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return exitError
+	}
+	return report.ExitCode()
+}
+
+// Reads the version and global variables of a server, or of a snapshot
+// file given in place of the server.
+func getServerVariables(serverAndPort string) (*ServerVariables, error) {
+	if isSnapshotFile(serverAndPort) {
+		snapshot, err := readSnapshot(serverAndPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		return readServerVariables(snapshot.Server, snapshot)
+	}
+	db, err := connectTo(serverAndPort)
+	if err != nil {
+		return nil, err
+	}
+	defer db.close()
+	return readServerVariables(serverAndPort, db)
+}
+
+// Writes the SQL script for the report to the given file, or to stdout.
+func emitSQLScript(report *DiffReport, path string) error {
+	if path == emitSQLToStdout {
+		return writeSQLScript(report, os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeSQLScript(report, file)
+	if err != nil {
+		_ = file.Close()
+		return err
 	}
-	// Compare the options maps and print results to stdout and stderr
-	// as appropriate. If --apply-changes, then also apply the changes
-	// to the server.
-	mysqlConfDiff(
-		db, confOptions, serverVariables, context.applyTheChanges,
-		os.Stdout, os.Stderr)
-	return 0
+	return file.Close()
+}
+
+// diffInput holds everything read from the config files and the server
+// that is needed to compare them.
+type diffInput struct {
+	version         MySQLVersion
+	configFiles     []string
+	persistedConfig string
+	confOptions     map[string]any
+	confSources     map[string]*OptionSource
+	serverVariables map[string]any
+	serverSources   map[string]*VariableSource
+	catalog         VariableCatalog
 }
 
 // Given the connection information defined in the run context, this
 // function connects to the MySQL server and returns an open connection.
 func getDB(context *RunContext) (db *dbConn, err error) {
+	db, err = connectTo(context.serverAndPort)
+	if err != nil {
+		return nil, err
+	}
+	db.applyMode = context.applyMode
+	return db, nil
+}
+
+// Connects to the MySQL server at the given address with the user and
+// password from the environment.
+func connectTo(serverAndPort string) (*dbConn, error) {
 	// Get user and password information
 	user, password, err := getMySQLUserInfo()
 	if err != nil {
@@ -100,95 +362,279 @@ func getDB(context *RunContext) (db *dbConn, err error) {
 	}
 
 	// Connect to the MySQL server
-	db, err = connect(fmt.Sprintf(
-		"%s:%s@tcp(%s)/", user, password, context.serverAndPort))
+	db, err := connect(fmt.Sprintf(
+		"%s:%s@tcp(%s)/", user, password, serverAndPort))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
 	}
 	return db, nil
 }
 
-// Given the my.cnf path and a database connection, this function reads
-// the my.cnf file and queries the server for its variables. It returns
-// these as two maps.
-func getOptionsFrom(configPath string, db *dbConn) (
-	confOptions map[string]any, serverVariables map[string]any, err error) {
+// Given the run context and a variable source, either a database connection
+// or a snapshot, this function reads the option groups of the my.cnf files
+// in order, layers any persisted variables on top, and reads the version
+// and variables of the server. It returns these along with where each
+// my.cnf option and server variable was set.
+func getOptionsFrom(context *RunContext, source variableSource, stderr io.Writer) (*diffInput, error) {
 	// Get the running MySQL version. This is necessary to interpret
 	// the configuration option blocks correctly.
-	version, err := db.getVersion()
+	version, err := source.getVersion()
 	if err != nil {
-		return nil, nil, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to read mysql version: %w", err)
 	}
 	// Get the variables of the running server.
-	serverVariables, err = db.getVariables()
+	serverVariables, err := source.getVariables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query MySQL for server variables: %w", err)
+	}
+	// Get where the server variables were set, where the server reports it.
+	// This is informational only, so failures are not fatal.
+	var serverSources map[string]*VariableSource
+	if version.hasVariablesInfo() {
+		serverSources, err = source.getVariableSources()
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: failed to query MySQL for variable sources: %v\n", err)
+		}
+	}
+	// Read my.cnf configuration files
+	configFiles, err := getOptionFiles(context)
+	if err != nil {
+		return nil, err
+	}
+	mysqlConfig, err := NewMySQLConfigFromFiles(configFiles)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to query MySQL for server variables: %w", err)
+		return nil, fmt.Errorf("failed to load MySQL config: %w", err)
 	}
-	// Read my.cnf configuration file
-	mysqlConfig, err := NewMySQLConfig(configPath)
+	// Limit the my.cnf options to the groups read by the running MySQL
+	// version
+	groups := context.optionGroups.ForVersion(version)
+	groups = append(groups, context.optionGroups.VersionRangeGroups(mysqlConfig.SectionTitles(), version)...)
+	confOptions := mysqlConfig.ComposeForGroups(groups)
+	confSources := mysqlConfig.SourcesForGroups(groups)
+	// Layer the variables saved with SET PERSIST on top
+	persistedConfig := ""
+	if context.persistedConfig != "" {
+		persistedConfig, err = applyPersistedConfig(
+			context.persistedConfig, serverVariables, confOptions, confSources, stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load persisted config: %w", err)
+		}
+	}
+	return &diffInput{
+		version:         version,
+		configFiles:     mysqlConfig.Files(),
+		persistedConfig: persistedConfig,
+		confOptions:     confOptions,
+		confSources:     confSources,
+		serverVariables: serverVariables,
+		serverSources:   serverSources,
+		catalog:         systemVariables.ForVersion(version),
+	}, nil
+}
+
+// Reads the mysqld-auto.cnf file at the given path and layers its variables
+// on top of the my.cnf options. If the path is 'datadir', the file is looked
+// up in the server's data directory and skipped if it doesn't exist. The
+// path of the file that was read is returned, or an empty string if none.
+func applyPersistedConfig(
+	persistedConfigPath string,
+	serverVariables map[string]any,
+	confOptions map[string]any,
+	confSources map[string]*OptionSource,
+	stderr io.Writer,
+) (string, error) {
+	// mysqld ignores mysqld-auto.cnf when persisted_globals_load is off
+	if serverVariables["PERSISTED_GLOBALS_LOAD"] == "OFF" {
+		_, _ = fmt.Fprintf(stderr, "Skipping persisted config: persisted_globals_load is OFF\n")
+		return "", nil
+	}
+	if persistedConfigPath == persistedConfigFromDatadir {
+		path, err := getPersistedConfigPath(serverVariables)
+		if err != nil {
+			return "", err
+		}
+		exists, err := fileExists(path)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			_, _ = fmt.Fprintf(stderr, "No persisted config found at %s\n", path)
+			return "", nil
+		}
+		persistedConfigPath = path
+	}
+	persistedConfig, err := NewPersistedConfig(persistedConfigPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to load MySQL config: %w", err)
+		return "", err
 	}
-	// Limit the my.cnf options to those for the running MySQL version
-	confOptions = mysqlConfig.ComposeForVersion(version)
-	return confOptions, serverVariables, nil
+	persistedConfig.ApplyTo(confOptions, confSources)
+	return persistedConfigPath, nil
 }
 
 // Given the my.cnf options map and server variables map, this function
-// compares the two and prints any differences to stdout. If the
-// --apply-changes flag is set, then the function will also apply the
-// changes to the server and print the change it made.
+// compares the two and returns the result for each option, along with where
+// it was set according to confSources and serverSources, and any lint notes
+// from the variable metadata in the catalog. The options are compared in
+// the given order (see sortOptionKeys). The differences are applied in the
+// same order by applyWithRollback.
 func mysqlConfDiff(
-	db *dbConn,
 	confOptions map[string]any,
 	serverVariables map[string]any,
-	applyTheChanges bool,
-	stdout, stderr io.Writer,
-) {
+	confSources map[string]*OptionSource,
+	serverSources map[string]*VariableSource,
+	catalog VariableCatalog,
+	orderBy string,
+) []*OptionResult {
+	var results []*OptionResult
 	// Loop through the my.cnf options and compare to the server variables
 	// watched by the user.
-	for key, optionValue := range confOptions {
+	for _, key := range sortOptionKeys(confOptions, confSources, orderBy) {
+		optionValue := confOptions[key]
+		result := &OptionResult{
+			Key:          key,
+			ConfigValue:  fmt.Sprint(optionValue),
+			ConfigSource: confSources[key],
+			ServerSource: serverSources[key],
+			Metadata:     catalog[key],
+		}
+		result.Notes = result.Metadata.lint(result.ConfigValue)
+		results = append(results, result)
 		// If the option is not in the server variables, then it is
-		// potentially invalid. Report this to the user.
+		// potentially invalid. Options with the loose- prefix are
+		// expected to be missing when the plugin providing them is not
+		// loaded.
 		serverValue, keyExists := serverVariables[key].(string)
 		if !keyExists {
-			_, _ = fmt.Fprintf(stderr,
-				"Warning: option '%s' in configuration file was "+
-					"not found in server variables\n", key)
+			result.Status = StatusMissing
+			if result.ConfigSource != nil && IsLooseOption(result.ConfigSource.Name) {
+				result.Status = StatusPluginNotLoaded
+			}
 			continue
 		}
-		if serverValue == optionValue {
+		result.ServerValue = serverValue
+		if compareValues(result.Metadata, result.ConfigValue, serverValue) {
+			result.Status = StatusEqual
 			continue // Nothing to do
 		}
-		// Handle ON and OFF in server variables' equality with 1
-		// and 0, respectively.
-		if serverValue == "ON" && optionValue == "1" {
-			continue // Nothing to do
+		// The server rounds some values, e.g. innodb_buffer_pool_size to
+		// a multiple of the chunk size, so these would never be equal
+		note, rounded := isEqualAfterRounding(result.Metadata, result.ConfigValue, serverValue, serverVariables)
+		if rounded {
+			result.Status = StatusEqual
+			result.Notes = append(result.Notes, note)
+			continue
 		}
-		if serverValue == "OFF" && optionValue == "0" {
-			continue // Nothing to do
+		result.Status = StatusDifferent
+	}
+	return results
+}
+
+// Applies the differences in the results to the server, in order, records
+// the outcome of each, and then verifies the applied changes.
+func applyChanges(db *dbConn, results []*OptionResult) {
+	for _, result := range results {
+		if result.Status != StatusDifferent {
+			continue
 		}
-		// Handle directories that end with a slash
-		if strings.HasSuffix(serverValue, "/") && serverValue[:len(serverValue)-1] == optionValue {
-			continue // Nothing to do
+		applyValue, _ := result.changeValues()
+		warnings, err := db.applySetting(
+			result.Key, applyValue, result.Metadata)
+		if err != nil {
+			recordApplyError(result, err)
+			continue
+		}
+		result.Status = StatusApplied
+		result.ApplyWarnings = warnings
+		// SET PERSIST_ONLY leaves the runtime value unchanged
+		if db.applyMode == applyModePersistOnly {
+			result.Status = StatusStaged
+		}
+	}
+	verifyAppliedSettings(db, results)
+}
+
+// Re-reads each applied variable from the server once all changes are
+// applied, and records whether the change took effect. MySQL may round or
+// clamp a value without failing the SET, e.g. innodb_buffer_pool_size is
+// rounded to a multiple of the chunk size, and a change may affect
+// variables applied earlier.
+func verifyAppliedSettings(db *dbConn, results []*OptionResult) {
+	for _, result := range results {
+		if result.Status != StatusApplied {
+			continue
 		}
-		// Report on any differences to console user
-		_, _ = fmt.Fprintf(stdout, "Difference found for: %s\n", key)
-		_, _ = fmt.Fprintf(stdout, "  my.cnf:    %s\n", optionValue)
-		_, _ = fmt.Fprintf(stdout, "  mysqld:    %s\n", serverValue)
-		// If the --apply-changes flag is provided, actually apply the changes
-		if applyTheChanges {
-			err := db.applySetting(
-				key, optionValue)
-			if err != nil {
-				_, _ = fmt.Fprintf(stderr, "Warning: Failed to SET variable: %v\n", err)
+		appliedValue, ok, err := db.getVariable(result.Key)
+		if err != nil || !ok {
+			if err == nil {
+				err = fmt.Errorf("variable not found")
 			}
-			_, _ = fmt.Fprintf(stdout, "Set variable:\n  %s = %s\n", key, optionValue)
+			result.ApplyWarnings = append(result.ApplyWarnings, fmt.Sprintf("failed to verify: %v", err))
+			continue
+		}
+		result.AppliedValue = &appliedValue
+		switch {
+		case compareValues(result.Metadata, result.ConfigValue, appliedValue):
+			// Applied as requested
+		case appliedValue == result.ServerValue:
+			result.Status = StatusNotApplied
+		default:
+			result.Status = StatusAdjusted
 		}
 	}
 }
 
+// The orders supported by --order-by.
+const (
+	orderByName   = "name"
+	orderBySource = "source"
+)
+
+// Returns the keys of the options map in the given order: either sorted by
+// name, or by where the options were set according to confSources, i.e. in
+// the order mysqld reads them. Options without a source come last, sorted
+// by name.
+func sortOptionKeys(
+	options map[string]any, confSources map[string]*OptionSource, orderBy string) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if orderBy == orderBySource {
+		sort.SliceStable(keys, func(i, j int) bool {
+			left, right := confSources[keys[i]], confSources[keys[j]]
+			if left == nil || right == nil {
+				return left != nil && right == nil
+			}
+			return left.readBefore(right)
+		})
+	}
+	return keys
+}
+
+// Reports whether a my.cnf option value is equal to a server variable value.
+// This is the comparator for variables without a more specific one (see
+// comparatorFor).
+func isEqualValue(optionValue, serverValue string) bool {
+	if serverValue == optionValue {
+		return true
+	}
+	// Handle ON and OFF in server variables' equality with 1
+	// and 0, respectively.
+	if serverValue == "ON" && optionValue == "1" {
+		return true
+	}
+	if serverValue == "OFF" && optionValue == "0" {
+		return true
+	}
+	// Handle directories that end with a slash
+	if strings.HasSuffix(serverValue, "/") && serverValue[:len(serverValue)-1] == optionValue {
+		return true
+	}
+	return false
+}
+
 // Given a map of options, this function returns a new map with the
 // keys normalized to the format used by the MySQL server.
 func normalizeKeys(input map[string]any) map[string]any {
@@ -200,6 +646,33 @@ func normalizeKeys(input map[string]any) map[string]any {
 	return result
 }
 
+// Given the diff results and the session server variables, this function
+// records the session value of the options whose session value differs from
+// their global value. Only variables with both scopes can differ.
+func addSessionValues(results []*OptionResult, sessionVariables map[string]any) {
+	for _, result := range results {
+		sessionValue, sessionExists := sessionVariables[result.Key].(string)
+		if result.Status == StatusMissing || result.Status == StatusPluginNotLoaded ||
+			!sessionExists || sessionValue == result.ServerValue {
+			continue
+		}
+		result.SessionValue = &sessionValue
+	}
+}
+
+// Given the diff results and the variables saved with SET PERSIST, this
+// function records the persisted value of the options about to be applied,
+// so that the rollback script can restore it.
+func addPersistedValues(results []*OptionResult, persistedVariables map[string]any) {
+	for _, result := range results {
+		persistedValue, persisted := persistedVariables[result.Key].(string)
+		if result.Status != StatusDifferent || !persisted {
+			continue
+		}
+		result.PersistedValue = &persistedValue
+	}
+}
+
 // Only watch certain settings, based on --watch-options.
 // This function effectively limits the original map to only the keys
 // that are in the watchedOptions map.
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,15 +20,14 @@ func TestMysqlConfDiff_DiffNoApply(t *testing.T) {
 	defer db.close()
 	confOptions := map[string]any{"key1": "value1"}
 	serverVariables := map[string]any{"key1": "value2"}
-	applyTheChanges := false
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges,
-		&stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
 	expectedInStdOut := []string{"Difference found"}
@@ -47,21 +47,24 @@ func TestMysqlConfDiff_DiffApply(t *testing.T) {
 
 	confOptions := map[string]any{"key1": "value1"}
 	serverVariables := map[string]any{"key1": "value2"}
-	applyTheChanges := true
 
 	// Set SQL expectation (assuming applySetting executes 'SET key = value' query)
 	m.ExpectExec("SET GLOBAL key1 = ?").WithArgs("value1").WillReturnResult(sqlmock.NewResult(1, 1))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("key1").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("key1", "value1"))
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges,
-		&stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	applyChanges(db, results)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
-	expectedInStdOut := []string{"Difference found", "Set variable", "key1 = value1"}
+	expectedInStdOut := []string{"Difference found", "Set variable", "key1 = value1", "applied"}
 	for _, expectedStr := range expectedInStdOut {
 		assert.True(t, strings.Contains(stdout.String(), expectedStr))
 	}
@@ -78,14 +81,14 @@ func TestMysqlConfDiff_NoDiff(t *testing.T) {
 
 	confOptions := map[string]any{"key1": "value1"}
 	serverVariables := map[string]any{"key1": "value1"}
-	applyTheChanges := false // Doesn't matter in this case as no changes will be applied anyway
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges, &stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
 	expectedStdout := "" // No differences should be reported
@@ -104,14 +107,14 @@ func TestMysqlConfDiff_NoDiff_ON_1(t *testing.T) {
 
 	confOptions := map[string]any{"key1": "1"}
 	serverVariables := map[string]any{"key1": "ON"}
-	applyTheChanges := false // Doesn't matter in this case as no changes will be applied anyway
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges, &stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
 	expectedStdout := "" // No differences should be reported
@@ -130,14 +133,14 @@ func TestMysqlConfDiff_NoDiff_OFF_0(t *testing.T) {
 
 	confOptions := map[string]any{"key1": "0"}
 	serverVariables := map[string]any{"key1": "OFF"}
-	applyTheChanges := false // Doesn't matter in this case as no changes will be applied anyway
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges, &stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
 	expectedStdout := "" // No differences should be reported
@@ -156,14 +159,14 @@ func TestMysqlConfDiff_NoDiff_DirectorySlash(t *testing.T) {
 
 	confOptions := map[string]any{"key1": "/my/dir"}
 	serverVariables := map[string]any{"key1": "/my/dir/"}
-	applyTheChanges := false // Doesn't matter in this case as no changes will be applied anyway
 
 	// Capture stdout and stderr
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	// Run function
-	mysqlConfDiff(db, confOptions, serverVariables, applyTheChanges, &stdout, &stderr)
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
 
 	// Check results
 	expectedStdout := "" // No differences should be reported
@@ -213,3 +216,360 @@ func TestLimitToWatchedOptions(t *testing.T) {
 		t.Errorf("limitToWatchedOptions() = %v, want %v", result, expected)
 	}
 }
+
+func TestMysqlConfDiff_MissingLooseOption(t *testing.T) {
+	// Prepare dependencies and inputs
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{"RPL_SEMI_SYNC_MASTER_ENABLED": "1", "KEY1": "value1"}
+	serverVariables := map[string]any{}
+	confSources := map[string]*OptionSource{
+		"RPL_SEMI_SYNC_MASTER_ENABLED": {
+			Name: "loose-rpl_semi_sync_master_enabled", Value: "1",
+			File: "my.cnf", Line: 3, Section: "mysqld",
+		},
+	}
+
+	// Capture stdout and stderr
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	// Run function
+	results := mysqlConfDiff(confOptions, serverVariables, confSources, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	// Check results
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "Plugin not loaded: loose option 'RPL_SEMI_SYNC_MASTER_ENABLED' (my.cnf:3 [mysqld])")
+	assert.Contains(t, stderr.String(), "Warning: option 'KEY1'")
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_DiffShowsSource(t *testing.T) {
+	// Prepare dependencies and inputs
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{"CONNECT_TIMEOUT": "60"}
+	serverVariables := map[string]any{"CONNECT_TIMEOUT": "30"}
+	confSources := map[string]*OptionSource{
+		"CONNECT_TIMEOUT": {
+			Name: "connect_timeout", Value: "60",
+			File: "/etc/mysql/conf.d/timeouts.cnf", Line: 12, Section: "mysqld-8.0",
+			Overrides: []*OptionSource{{
+				Name: "connect_timeout", Value: "45",
+				File: "/etc/mysql/my.cnf", Line: 3, Section: "mysqld",
+			}},
+		},
+	}
+
+	// Capture stdout and stderr
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	// Run function
+	results := mysqlConfDiff(confOptions, serverVariables, confSources, nil, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	// Check results
+	expected := "Difference found for: CONNECT_TIMEOUT\n" +
+		"  my.cnf:    60 (/etc/mysql/conf.d/timeouts.cnf:12 [mysqld-8.0])\n" +
+		"  overrode:  45 (/etc/mysql/my.cnf:3 [mysqld])\n" +
+		"  mysqld:    30\n"
+	assert.Equal(t, expected, stdout.String())
+	assert.Empty(t, stderr.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestAddSessionValues(t *testing.T) {
+	results := []*OptionResult{
+		{Key: "SQL_MODE", ConfigValue: "STRICT_TRANS_TABLES", ServerValue: "STRICT_TRANS_TABLES", Status: StatusEqual},
+		{Key: "MAX_CONNECTIONS", ConfigValue: "151", ServerValue: "151", Status: StatusEqual},
+	}
+	sessionVariables := map[string]any{"SQL_MODE": "", "MAX_CONNECTIONS": "151"}
+
+	addSessionValues(results, sessionVariables)
+	require.NotNil(t, results[0].SessionValue)
+	require.Equal(t, "", *results[0].SessionValue)
+	require.Nil(t, results[1].SessionValue)
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	expected := "Session value differs from global for: SQL_MODE\n" +
+		"  global:    STRICT_TRANS_TABLES\n" +
+		"  session:   \n"
+	assert.Equal(t, expected, stdout.String())
+}
+
+func TestMysqlConfDiff_DiffShowsServerSource(t *testing.T) {
+	// Prepare dependencies and inputs
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{"MAX_CONNECTIONS": "500"}
+	serverVariables := map[string]any{"MAX_CONNECTIONS": "1000"}
+	serverSources := map[string]*VariableSource{
+		"MAX_CONNECTIONS": {
+			Source: "DYNAMIC", SetTime: "2023-01-02 03:12:00.000000",
+			SetUser: "dba_bob", SetHost: "localhost",
+		},
+	}
+
+	// Capture stdout and stderr
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	// Run function
+	results := mysqlConfDiff(confOptions, serverVariables, nil, serverSources, nil, orderByName)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	// Check results
+	assert.Contains(t, stdout.String(),
+		"  mysqld:    1000 (DYNAMIC, set by dba_bob@localhost at 2023-01-02 03:12:00.000000)\n")
+	assert.Empty(t, stderr.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_SortedByName(t *testing.T) {
+	// Prepare dependencies and inputs
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{"KEY3": "value", "KEY1": "value", "KEY2": "value"}
+	serverVariables := map[string]any{"KEY1": "other", "KEY2": "other", "KEY3": "other"}
+
+	// Apply in the same order as the results are reported
+	m.ExpectExec("SET GLOBAL KEY1 = ?").WithArgs("value").WillReturnResult(sqlmock.NewResult(1, 1))
+	m.ExpectExec("SET GLOBAL KEY2 = ?").WithArgs("value").WillReturnResult(sqlmock.NewResult(1, 1))
+	m.ExpectExec("SET GLOBAL KEY3 = ?").WithArgs("value").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Run function
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	applyChanges(db, results)
+
+	// Check results
+	require.Len(t, results, 3)
+	assert.Equal(t, "KEY1", results[0].Key)
+	assert.Equal(t, "KEY2", results[1].Key)
+	assert.Equal(t, "KEY3", results[2].Key)
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestSortOptionKeysBySource(t *testing.T) {
+	cfg, err := NewMySQLConfig([]byte(`[mysqld]
+key_b=1
+key_c=1
+key_a=1
+`))
+	require.NoError(t, err)
+	version := MySQLVersion{Major: 8, Minor: 0, Patch: 28}
+	confOptions := cfg.ComposeForVersion(version)
+	confSources := cfg.SourcesForVersion(version)
+	persisted, err := NewPersistedConfig([]byte(`{"mysql_server": {"key_b": {"Value": "2"}}}`))
+	require.NoError(t, err)
+	persisted.ApplyTo(confOptions, confSources)
+	confOptions = normalizeKeys(confOptions)
+	confOptions["KEY_D"] = "1"
+
+	keys := sortOptionKeys(confOptions, confSources, orderBySource)
+	assert.Equal(t, []string{"KEY_C", "KEY_A", "KEY_B", "KEY_D"}, keys)
+
+	keys = sortOptionKeys(confOptions, confSources, orderByName)
+	assert.Equal(t, []string{"KEY_A", "KEY_B", "KEY_C", "KEY_D"}, keys)
+}
+
+func TestMysqlConfDiff_ApplyIsVerified(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{
+		"INNODB_BUFFER_POOL_SIZE": "1000000000",
+		"MAX_CONNECTIONS":         "200000",
+	}
+	serverVariables := map[string]any{
+		"INNODB_BUFFER_POOL_SIZE": "134217728",
+		"MAX_CONNECTIONS":         "151",
+	}
+	noWarnings := sqlmock.NewRows([]string{"Level", "Code", "Message"})
+	m.ExpectExec("SET GLOBAL INNODB_BUFFER_POOL_SIZE = ?").WithArgs(1000000000).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(noWarnings)
+	m.ExpectExec("SET GLOBAL MAX_CONNECTIONS = ?").WithArgs(200000).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(
+		sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+			AddRow("Warning", 1292, "Truncated incorrect max_connections value: '200000'"))
+	// The server rounds the buffer pool size, and caps max_connections
+	// without changing it
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("INNODB_BUFFER_POOL_SIZE").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("innodb_buffer_pool_size", "1073741824"))
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("MAX_CONNECTIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("max_connections", "151"))
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	applyChanges(db, results)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	require.Equal(t, StatusAdjusted, results[0].Status)
+	require.Equal(t, "1073741824", *results[0].AppliedValue)
+	require.Equal(t, StatusNotApplied, results[1].Status)
+	assert.Contains(t, stdout.String(), "applied with adjustment (requested 1000000000, got 1073741824)")
+	assert.Contains(t, stdout.String(), "Not applied: MAX_CONNECTIONS (requested 200000, got 151)\n")
+	assert.NotContains(t, stdout.String(), "MAX_CONNECTIONS = 200000")
+	assert.Equal(t, "Warning: SET variable MAX_CONNECTIONS: "+
+		"Warning 1292: Truncated incorrect max_connections value: '200000'\n", stderr.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_ApplyReadOnly(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	confOptions := map[string]any{"DATADIR": "/data/mysql"}
+	serverVariables := map[string]any{"DATADIR": "/var/lib/mysql/"}
+	m.ExpectExec("SET GLOBAL DATADIR = ?").WithArgs("/data/mysql").WillReturnError(
+		&mysql.MySQLError{Number: 1238, Message: "Variable 'datadir' is a read only variable"})
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	applyChanges(db, results)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	// A failed SET is not reported as set, nor verified
+	require.Equal(t, StatusReadOnly, results[0].Status)
+	require.Equal(t, uint16(1238), results[0].ApplyErrorNumber)
+	assert.NotContains(t, stdout.String(), "Set variable")
+	assert.Contains(t, stderr.String(), "MySQL error 1238: the variable is read-only")
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_ApplyPersistOnly(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn, applyMode: applyModePersistOnly}
+	defer db.close()
+
+	confOptions := map[string]any{"INNODB_LOG_FILE_SIZE": "1073741824"}
+	serverVariables := map[string]any{"INNODB_LOG_FILE_SIZE": "50331648"}
+	m.ExpectExec("SET PERSIST_ONLY INNODB_LOG_FILE_SIZE = ?").WithArgs(1073741824).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, nil, orderByName)
+	applyChanges(db, results)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	// The runtime value is unchanged until the restart, so it isn't verified
+	require.Equal(t, StatusStaged, results[0].Status)
+	assert.Contains(t, stdout.String(),
+		"Persisted variable for the next restart:\n  INNODB_LOG_FILE_SIZE = 1073741824\n")
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_UsesVariableMetadata(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	confOptions := map[string]any{
+		"INNODB_LOG_FILE_SIZE": "1073741824",
+		"LONG_QUERY_TIME":      "2",
+	}
+	serverVariables := map[string]any{
+		"INNODB_LOG_FILE_SIZE": "50331648",
+		"LONG_QUERY_TIME":      "10.000000",
+	}
+	// Floats are sent as floats, and variables that are not dynamic are
+	// not sent at all
+	m.ExpectExec("SET GLOBAL LONG_QUERY_TIME = ?").WithArgs(2.0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("LONG_QUERY_TIME").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("long_query_time", "2.000000"))
+
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, catalog, orderByName)
+	applyChanges(db, results)
+
+	require.Equal(t, StatusReadOnly, results[0].Status)
+	require.Equal(t, "INNODB_LOG_FILE_SIZE is not dynamic: it can only be set at startup, "+
+		"or with --apply-mode persist-only", results[0].ApplyError)
+	require.Equal(t, []string{"deprecated since MySQL 8.0.30"}, results[0].Notes)
+	require.Equal(t, "LONG_QUERY_TIME", results[1].Key)
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_ApplyFlags(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	confOptions := map[string]any{"OPTIMIZER_SWITCH": "mrr=on,hash_join=off,derived_merge=off"}
+	serverVariables := map[string]any{"OPTIMIZER_SWITCH": "index_merge=on,mrr=on,derived_merge=on,hash_join=on"}
+	// Only the flags that differ are set
+	m.ExpectExec("SET GLOBAL OPTIMIZER_SWITCH = ?").WithArgs("hash_join=off,derived_merge=off").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("OPTIMIZER_SWITCH").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("optimizer_switch", "index_merge=on,mrr=on,derived_merge=off,hash_join=off"))
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, catalog, orderByName)
+	applyChanges(db, results)
+	writeTextReport(&DiffReport{Options: results}, &stdout, &stderr)
+
+	require.Equal(t, StatusApplied, results[0].Status)
+	assert.Contains(t, stdout.String(), "  my.cnf:    hash_join=off,derived_merge=off\n")
+	assert.Contains(t, stdout.String(), "  mysqld:    hash_join=on,derived_merge=on\n")
+	assert.Contains(t, stdout.String(), "Set variable:\n  OPTIMIZER_SWITCH = hash_join=off,derived_merge=off\n")
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestMysqlConfDiff_EqualAfterRounding(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	confOptions := map[string]any{
+		"INNODB_BUFFER_POOL_SIZE": "1000000000",
+		"JOIN_BUFFER_SIZE":        "300000",
+	}
+	serverVariables := map[string]any{
+		"INNODB_BUFFER_POOL_CHUNK_SIZE": "134217728",
+		"INNODB_BUFFER_POOL_INSTANCES":  "1",
+		"INNODB_BUFFER_POOL_SIZE":       "1073741824",
+		"JOIN_BUFFER_SIZE":              "262144",
+	}
+
+	results := mysqlConfDiff(confOptions, serverVariables, nil, nil, catalog, orderByName)
+
+	// The buffer pool size is rounded up to 1G, while the join buffer size
+	// is rounded down to 299904, which still differs
+	require.Equal(t, StatusEqual, results[0].Status)
+	require.Equal(t, []string{"value 1000000000 is rounded by the server to 1073741824"}, results[0].Notes)
+	require.Equal(t, StatusDifferent, results[1].Status)
+}
@@ -6,65 +6,158 @@ import (
 	"os"
 	"strconv"
 	"strings"
-
-	"github.com/go-ini/ini"
+	"time"
 )
 
 // MySQLConfig represents a loaded MySQL config file.
 type MySQLConfig struct {
 	sectionTitles []string
-	cfg           *ini.File
+	options       []*OptionSource
+	files         []string
+}
+
+// OptionSource records a single option assignment read from a config file,
+// along with where it was set.
+type OptionSource struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Section string `json:"section"`
+	// Overrides holds the earlier definitions of the same option that were
+	// replaced by this one, oldest first.
+	Overrides []*OptionSource `json:"overrides,omitempty"`
+	// Persisted is set for options saved with SET PERSIST, which are read
+	// from mysqld-auto.cnf rather than my.cnf. PersistedBy and PersistedAt
+	// record who saved the option and when, if known.
+	Persisted   bool       `json:"persisted,omitempty"`
+	PersistedBy string     `json:"persisted_by,omitempty"`
+	PersistedAt *time.Time `json:"persisted_at,omitempty"`
+	// The position of the option among all options read, used to order
+	// options by where they were set.
+	readOrder int
+}
+
+// Reports whether this option was read before the other option. Persisted
+// options are read after all my.cnf options, like mysqld does.
+func (s *OptionSource) readBefore(other *OptionSource) bool {
+	if s.Persisted != other.Persisted {
+		return !s.Persisted
+	}
+	return s.readOrder < other.readOrder
 }
 
+// String returns the location of the option, e.g. `my.cnf:12 [mysqld]` or
+// `mysqld-auto.cnf [persisted by root@localhost at 2023-01-02T03:04:05Z]`.
+func (s *OptionSource) String() string {
+	if s.Persisted {
+		description := "persisted"
+		if s.PersistedBy != "" {
+			description += " by " + s.PersistedBy
+		}
+		if s.PersistedAt != nil {
+			description += " at " + s.PersistedAt.Format(time.RFC3339)
+		}
+		return fmt.Sprintf("%s [%s]", describeConfigPath(s.File), description)
+	}
+	return fmt.Sprintf("%s:%d [%s]", describeConfigPath(s.File), s.Line, s.Section)
+}
+
+// The section title given to options that appear before any section.
+const defaultSectionTitle = "DEFAULT"
+
 // NewMySQLConfig creates a new MySQLConfig object from the given config,
 // which can be either the path to the config file or []byte with the config
 // file contents.
 func NewMySQLConfig(config any) (*MySQLConfig, error) {
-	// Read the config file contents and handle polymorphic type
-	confContents, err := readFile(config)
+	parser := &configParser{}
+	err := parser.parseConfig(config)
 	if err != nil {
 		return nil, err
 	}
-	// Remove unsupported lines and directives
-	confContents = clean(confContents)
-	// Parse the resulting, cleaned config
-	cfg, err := ini.Load(confContents)
-	if err != nil {
-		return nil, err
+	return parser.result(), nil
+}
+
+// NewMySQLConfigFromFiles creates a new MySQLConfig object from several
+// config files, read in the given order. Options in later files take
+// precedence over those in earlier files.
+func NewMySQLConfigFromFiles(configPaths []string) (*MySQLConfig, error) {
+	parser := &configParser{}
+	for _, configPath := range configPaths {
+		err := parser.parseConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
 	}
-	// Get the section titles
-	sectionTitles := cfg.SectionStrings()
-	// Construct and return the new object
-	return &MySQLConfig{
-		sectionTitles: sectionTitles,
-		cfg:           cfg,
-	}, nil
+	return parser.result(), nil
+}
+
+// Files returns the paths of the config files that were read, including
+// included files, in the order they were read.
+func (c *MySQLConfig) Files() []string {
+	return c.files
+}
+
+// SectionTitles returns the titles of the sections in the config files,
+// in the order they were first read.
+func (c *MySQLConfig) SectionTitles() []string {
+	return c.sectionTitles
 }
 
 // ComposeForVersion composes a map of all the MySQL config settings that
-// should be applied for the given MySQL version. The map keys are the MySQL
-// config option names.
+// should be applied for the given MySQL version, reading the default
+// option groups. The map keys are the MySQL config option names.
 func (c *MySQLConfig) ComposeForVersion(version MySQLVersion) map[string]any {
+	return c.ComposeForGroups(defaultOptionGroups(version.Flavor).ForVersion(version))
+}
+
+// ComposeForGroups composes a map of all the MySQL config settings set in
+// the given option groups. The map keys are the MySQL config option names.
+func (c *MySQLConfig) ComposeForGroups(groups []string) map[string]any {
 	allSettings := make(map[string]any)
-	for _, sectionTitle := range c.sectionTitles {
-		if !isOptionBlockMatch(version, sectionTitle) {
+	for _, source := range c.SourcesForGroups(groups) {
+		allSettings[source.Name] = normalize(source.Value)
+	}
+	return allSettings
+}
+
+// SourcesForVersion returns where each of the settings composed by
+// ComposeForVersion was set.
+func (c *MySQLConfig) SourcesForVersion(version MySQLVersion) map[string]*OptionSource {
+	return c.SourcesForGroups(defaultOptionGroups(version.Flavor).ForVersion(version))
+}
+
+// SourcesForGroups returns where each of the settings composed by
+// ComposeForGroups was set. The map keys are the MySQL server variable
+// names. Like mysqld, options are applied in the order they were read,
+// regardless of their group, so the last definition of an option wins.
+func (c *MySQLConfig) SourcesForGroups(groups []string) map[string]*OptionSource {
+	sources := make(map[string]*OptionSource)
+	for _, option := range c.options {
+		if !isGroupMatch(groups, option.Section) {
 			continue
 		}
-		settings := c.cfg.Section(sectionTitle).KeysHash()
-
-		for key, value := range settings {
-			allSettings[key] = normalize(value)
+		key := GetVariableKeyFrom(option.Name)
+		source := *option
+		if previous, ok := sources[key]; ok {
+			overridden := *previous
+			overridden.Overrides = nil
+			source.Overrides = append(append([]*OptionSource{}, previous.Overrides...), &overridden)
 		}
+		sources[key] = &source
 	}
-	return allSettings
+	return sources
 }
 
 func isOptionBlockMatch(version MySQLVersion, sectionTitle string) bool {
-	if sectionTitle == "mysqld" {
-		return true
-	}
-	if sectionTitle == fmt.Sprintf("mysqld-%d.%d", version.Major, version.Minor) {
-		return true
+	return isGroupMatch(defaultOptionGroups(version.Flavor).ForVersion(version), sectionTitle)
+}
+
+func isGroupMatch(groups []string, sectionTitle string) bool {
+	for _, group := range groups {
+		if group == sectionTitle {
+			return true
+		}
 	}
 	return false
 }
@@ -90,30 +183,203 @@ func readFile(config any) ([]byte, error) {
 	return confContents, nil
 }
 
-// Removes lines from the config file that are not supported by the
-// utility.
-func clean(configContents []byte) []byte {
-	lines := strings.Split(string(configContents), "\n")
-	var newLines []string
-	for _, line := range lines {
-		isSection := strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")
-		isEmpty := strings.TrimSpace(line) == ""
-		hasEquals := strings.Contains(line, "=")
-		isIncludeDirective := strings.HasPrefix(line, "!include")
-		// Remove options that don't have a '=' in them (boolean options)
-		// This is a workaround for the fact that the go-ini library
-		// handles these options ambiguously.
-		if !isSection && !isEmpty && !hasEquals {
+// configParser reads MySQL option files into a flat list of options, in
+// the order mysqld would read them.
+type configParser struct {
+	sectionTitles []string
+	options       []*OptionSource
+	files         []string
+}
+
+// Reads and parses the given config, which can be either the path to the
+// config file or []byte with the config file contents.
+func (p *configParser) parseConfig(config any) error {
+	// Read the config file contents and handle polymorphic type
+	confContents, err := readFile(config)
+	if err != nil {
+		return err
+	}
+	// Parse the config, following any include directives
+	configPath, _ := config.(string)
+	stack, err := newIncludeStack(configPath)
+	if err != nil {
+		return err
+	}
+	return p.parse(confContents, configPath, stack)
+}
+
+// Returns the MySQLConfig built from everything parsed so far.
+func (p *configParser) result() *MySQLConfig {
+	return &MySQLConfig{
+		sectionTitles: p.sectionTitles,
+		options:       p.options,
+		files:         p.files,
+	}
+}
+
+// Parses the given config contents, which were read from configPath (empty
+// if the contents did not come from a file). The stack holds the absolute
+// paths of the files currently being parsed and is used to detect include
+// cycles.
+func (p *configParser) parse(contents []byte, configPath string, stack []string) error {
+	if configPath != "" {
+		p.files = append(p.files, configPath)
+	}
+	section := defaultSectionTitle
+	p.addSection(section)
+	for i, line := range strings.Split(string(contents), "\n") {
+		lineNumber := i + 1
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
 			continue
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				return fmt.Errorf("%s:%d: invalid section title: %s",
+					describeConfigPath(configPath), lineNumber, trimmed)
+			}
+			section = strings.TrimSpace(trimmed[1:end])
+			p.addSection(section)
+		case strings.HasPrefix(trimmed, "!"):
+			err := p.include(trimmed, configPath, lineNumber, stack)
+			if err != nil {
+				return err
+			}
+		default:
+			name, value := parseOptionLine(trimmed)
+			if name == "" {
+				continue
+			}
+			p.options = append(p.options, &OptionSource{
+				Name:      name,
+				Value:     value,
+				File:      configPath,
+				Line:      lineNumber,
+				Section:   section,
+				readOrder: len(p.options),
+			})
 		}
-		// This utility does not support include directives at the
-		// moment, so remove them.
-		if isIncludeDirective {
-			continue
+	}
+	return nil
+}
+
+// Records a section title, unless it has been seen before.
+func (p *configParser) addSection(title string) {
+	for _, existing := range p.sectionTitles {
+		if existing == title {
+			return
+		}
+	}
+	p.sectionTitles = append(p.sectionTitles, title)
+}
+
+// Splits an option line into the option name and its value. Options
+// without a value are treated as booleans.
+func parseOptionLine(line string) (name, value string) {
+	equals := strings.Index(line, "=")
+	comment := strings.Index(line, "#")
+	if equals < 0 || (comment >= 0 && comment < equals) {
+		return parseBareOption(line)
+	}
+	name = strings.TrimSpace(line[:equals])
+	value = parseOptionValue(strings.TrimSpace(line[equals+1:]))
+	return name, value
+}
+
+// Parses an option value the way mysqld does: quoted values are taken
+// verbatim up to the closing quote, while unquoted values end at a `#`
+// comment. Escape sequences are resolved in both cases.
+func parseOptionValue(raw string) string {
+	if len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'') {
+		quote := raw[0]
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\\' {
+				i++
+				continue
+			}
+			if raw[i] == quote {
+				return unescapeOptionValue(raw[1:i])
+			}
+		}
+	}
+	if i := strings.Index(raw, "#"); i >= 0 {
+		raw = raw[:i]
+	}
+	return unescapeOptionValue(strings.TrimSpace(raw))
+}
+
+// Resolves the escape sequences mysqld supports in option values. Unknown
+// escape sequences are kept as they are.
+func unescapeOptionValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	escapes := map[byte]byte{
+		'b': '\b', 't': '\t', 'n': '\n', 'r': '\r', 's': ' ',
+		'"': '"', '\'': '\'', '\\': '\\',
+	}
+	var result strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			if unescaped, ok := escapes[value[i+1]]; ok {
+				result.WriteByte(unescaped)
+				i++
+				continue
+			}
+		}
+		result.WriteByte(value[i])
+	}
+	return result.String()
+}
+
+// Option names that start with one of the boolean prefixes but are options
+// in their own right. mysqld matches full option names before it looks for
+// a prefix, so these are never rewritten.
+var prefixedOptionNames = map[string]bool{
+	"SKIP_EXTERNAL_LOCKING": true,
+	"SKIP_GRANT_TABLES":     true,
+	"SKIP_NAME_RESOLVE":     true,
+	"SKIP_NETWORKING":       true,
+	"SKIP_REPLICA_START":    true,
+	"SKIP_SHOW_DATABASE":    true,
+	"SKIP_SLAVE_START":      true,
+}
+
+// Converts a bare boolean option, such as `skip-name-resolve` or
+// `disable-log-bin`, into a key and an ON or OFF value. Like mysqld, the
+// `skip-` and `disable-` prefixes turn the option off and the `enable-`
+// prefix turns it on. These prefixes come after any `loose-` prefix, which
+// is kept, e.g. `loose-skip-log-bin` is `loose-log-bin` turned off. An empty
+// key is returned if the line is not an option.
+func parseBareOption(line string) (key, value string) {
+	// Drop any trailing comment
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	key = strings.TrimSpace(line)
+	if key == "" || prefixedOptionNames[GetVariableKeyFrom(key)] {
+		return key, "ON"
+	}
+	loose := ""
+	name := key
+	if IsLooseOption(key) {
+		loose, name = key[:len(loosePrefix)], key[len(loosePrefix):]
+	}
+	prefixes := []struct {
+		prefix string
+		value  string
+	}{
+		{"SKIP_", "OFF"},
+		{"DISABLE_", "OFF"},
+		{"ENABLE_", "ON"},
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(GetVariableKeyFrom(name), p.prefix) {
+			return loose + name[len(p.prefix):], p.value
 		}
-		newLines = append(newLines, line)
 	}
-	return []byte(strings.Join(newLines, "\n"))
+	return key, "ON"
 }
 
 // Normalizes values that are allowed in my.cnf but are not allowed in
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	includeDirective    = "!include"
+	includeDirDirective = "!includedir"
+)
+
+// Returns the initial include stack for the config read from configPath,
+// which is empty if the config did not come from a file.
+func newIncludeStack(configPath string) ([]string, error) {
+	if configPath == "" {
+		return nil, nil
+	}
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return []string{absPath}, nil
+}
+
+// Handles an `!include` or `!includedir` directive found at the given line
+// of configPath by parsing the referenced files in place, the same way
+// mysqld reads them. Relative include targets are resolved against the
+// directory of the including file. Unknown directives are ignored.
+func (p *configParser) include(line, configPath string, lineNumber int, stack []string) error {
+	directive, target, ok := parseIncludeDirective(line)
+	if !ok {
+		return nil
+	}
+	// Find the files referenced by the directive
+	target = resolveIncludePath(configPath, target)
+	files := []string{target}
+	if directive == includeDirDirective {
+		var err error
+		files, err = listIncludeDir(target)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", describeConfigPath(configPath), lineNumber, err)
+		}
+	}
+	// Parse each included file. mysqld keeps reading options into the
+	// including file's current section afterwards, which the caller does.
+	for _, file := range files {
+		err := p.parseIncludedFile(file, stack)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", describeConfigPath(configPath), lineNumber, err)
+		}
+	}
+	return nil
+}
+
+// Splits a trimmed config line into its include directive and target. The
+// ok result is false if the line is not an include directive.
+func parseIncludeDirective(line string) (directive, target string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", "", false
+	}
+	if fields[0] != includeDirective && fields[0] != includeDirDirective {
+		return "", "", false
+	}
+	target = strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	return fields[0], target, true
+}
+
+// Resolves an include target relative to the directory of the including
+// file. Absolute targets are returned unchanged.
+func resolveIncludePath(configPath, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+	return filepath.Join(filepath.Dir(configPath), target)
+}
+
+// Lists the option files in an `!includedir` directory. Like mysqld, only
+// files ending in `.cnf` are read, in lexical order.
+func listIncludeDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read include directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".cnf") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Reads and parses an included file, failing if the file is already being
+// parsed further up the stack.
+func (p *configParser) parseIncludedFile(path string, stack []string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, parent := range stack {
+		if parent == absPath {
+			cycle := append(append([]string{}, stack...), absPath)
+			return fmt.Errorf("include cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read included file: %w", err)
+	}
+	return p.parse(contents, path, append(stack, absPath))
+}
+
+// Returns a printable name for the config being read, for error messages.
+func describeConfigPath(configPath string) string {
+	if configPath == "" {
+		return "<config>"
+	}
+	return configPath
+}
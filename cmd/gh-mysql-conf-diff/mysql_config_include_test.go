@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+}
+
+func TestIncludeFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "my.cnf"), `
+[mysqld]
+key1=value1
+!include extra.cnf
+key3=value3
+`)
+	writeTestFile(t, filepath.Join(dir, "extra.cnf"), `
+[mysqld]
+key1=overridden
+[client]
+key2=value2
+`)
+	cfg, err := NewMySQLConfig(filepath.Join(dir, "my.cnf"))
+	require.NoError(t, err)
+
+	actual := cfg.ComposeForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28})
+	expected := map[string]any{
+		"key1": "overridden",
+		"key3": "value3",
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestIncludeDirLexicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "my.cnf"), `
+[mysqld]
+key1=value1
+!includedir conf.d
+`)
+	writeTestFile(t, filepath.Join(dir, "conf.d", "b.cnf"), "[mysqld]\nkey1=from_b\n")
+	writeTestFile(t, filepath.Join(dir, "conf.d", "a.cnf"), "[mysqld]\nkey1=from_a\nkey2=from_a\n")
+	writeTestFile(t, filepath.Join(dir, "conf.d", "c.txt"), "[mysqld]\nkey2=ignored\n")
+
+	cfg, err := NewMySQLConfig(filepath.Join(dir, "my.cnf"))
+	require.NoError(t, err)
+
+	actual := cfg.ComposeForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28})
+	expected := map[string]any{
+		"key1": "from_b",
+		"key2": "from_a",
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.cnf"), "[mysqld]\n!include b.cnf\n")
+	writeTestFile(t, filepath.Join(dir, "b.cnf"), "[mysqld]\n!include a.cnf\n")
+
+	_, err := NewMySQLConfig(filepath.Join(dir, "a.cnf"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "include cycle detected")
+}
+
+func TestIncludeMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "my.cnf")
+	writeTestFile(t, configPath, "[mysqld]\nkey1=value1\n!include missing.cnf\n")
+
+	_, err := NewMySQLConfig(configPath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), configPath+":3:")
+}
+
+func TestIncludeSources(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "my.cnf")
+	includedPath := filepath.Join(dir, "conf.d", "timeouts.cnf")
+	writeTestFile(t, configPath, "[mysqld]\nconnect_timeout=30\n!includedir conf.d\n")
+	writeTestFile(t, includedPath, "# Timeouts\n[mysqld-8.0]\nconnect_timeout=60\n")
+
+	cfg, err := NewMySQLConfig(configPath)
+	require.NoError(t, err)
+
+	sources := cfg.SourcesForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28})
+	require.Equal(t, includedPath+":3 [mysqld-8.0]", sources["CONNECT_TIMEOUT"].String())
+	require.Equal(t, configPath+":2 [mysqld]", sources["CONNECT_TIMEOUT"].Overrides[0].String())
+}
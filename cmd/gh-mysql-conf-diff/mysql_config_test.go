@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -155,38 +156,115 @@ func TestVersionNotMatch(t *testing.T) {
 	require.Equal(t, expected, result)
 }
 
-func TestCleanRequireEquals(t *testing.T) {
+func TestParseOptionLine(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    []byte
-		expected []byte
+		name          string
+		line          string
+		expectedName  string
+		expectedValue string
 	}{
-		{
-			name:     "when line does not contain '='",
-			input:    []byte("[test]\nfoo\n"),
-			expected: []byte("[test]\n"),
-		},
-		{
-			name:     "when line contains '='",
-			input:    []byte("[test]\nfoo = bar\n"),
-			expected: []byte("[test]\nfoo = bar\n"),
-		},
+		{"when line does not contain '='", "foo", "foo", "ON"},
+		{"when line contains '='", "foo = bar", "foo", "bar"},
+		{"when value has a comment", "foo = bar # baz", "foo", "bar"},
+		{"when value is double quoted", `foo = "bar # baz"`, "foo", "bar # baz"},
+		{"when value is single quoted", `foo = 'a=b'`, "foo", "a=b"},
+		{"when value has escapes", `foo = "C:\\data\tdir"`, "foo", "C:\\data\tdir"},
+		{"when comment contains '='", "foo # a=b", "foo", "ON"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := clean(tt.input)
-			require.Equal(t, string(tt.expected), string(result))
+			name, value := parseOptionLine(tt.line)
+			require.Equal(t, tt.expectedName, name)
+			require.Equal(t, tt.expectedValue, value)
 		})
 	}
 }
 
-func TestCleanIncludes(t *testing.T) {
-	input := []byte("[test]\nfoo = bar\n!includedir /etc/mysql/conf.d/\n")
-	expected := []byte("[test]\nfoo = bar\n")
+func TestParseSkipsCommentsAndUnknownDirectives(t *testing.T) {
+	cfg, err := NewMySQLConfig([]byte("[test]\nfoo = bar\n# baz\n; qux\n!unknown directive\n"))
+	require.NoError(t, err)
 
-	result := clean(input)
-	require.Equal(t, string(expected), string(result))
+	require.Len(t, cfg.options, 1)
+	require.Equal(t, "foo", cfg.options[0].Name)
+	require.Equal(t, "bar", cfg.options[0].Value)
+}
+
+func TestSourcesForVersion(t *testing.T) {
+	cfg, err := NewMySQLConfig(
+		[]byte(`[mysqld]
+connect_timeout=30
+
+[mysqld-8.0]
+connect-timeout=60
+
+[mysqld-5.7]
+connect_timeout=10
+`),
+	)
+	require.NoError(t, err)
+
+	sources := cfg.SourcesForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28})
+	source := sources["CONNECT_TIMEOUT"]
+	require.NotNil(t, source)
+	require.Equal(t, "60", source.Value)
+	require.Equal(t, "<config>:5 [mysqld-8.0]", source.String())
+	require.Len(t, source.Overrides, 1)
+	require.Equal(t, "30", source.Overrides[0].Value)
+	require.Equal(t, "<config>:2 [mysqld]", source.Overrides[0].String())
+
+	// The overridden spelling of the option is not composed
+	expected := map[string]any{"connect-timeout": "60"}
+	require.Equal(t, expected, cfg.ComposeForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28}))
+}
+
+func TestParseBareOption(t *testing.T) {
+	tests := []struct {
+		line          string
+		expectedKey   string
+		expectedValue string
+	}{
+		{"performance_schema", "performance_schema", "ON"},
+		{"log-bin", "log-bin", "ON"},
+		{"skip-log-bin", "log-bin", "OFF"},
+		{"disable-log-bin", "log-bin", "OFF"},
+		{"disable_log_bin", "log_bin", "OFF"},
+		{"enable-performance-schema", "performance-schema", "ON"},
+		{"skip-name-resolve", "skip-name-resolve", "ON"},
+		{"skip_networking # no TCP", "skip_networking", "ON"},
+		{"loose-skip-log-bin", "loose-log-bin", "OFF"},
+		{"loose-disable-log-bin", "loose-log-bin", "OFF"},
+		{"loose_enable_performance_schema", "loose_performance_schema", "ON"},
+		{"loose-skip-name-resolve", "loose-skip-name-resolve", "ON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			key, value := parseBareOption(tt.line)
+			require.Equal(t, tt.expectedKey, key)
+			require.Equal(t, tt.expectedValue, value)
+		})
+	}
+}
+
+func TestComposeBareOptions(t *testing.T) {
+	cfg, err := NewMySQLConfig(
+		[]byte(`
+[mysqld]
+skip-name-resolve
+disable-log-bin
+performance_schema
+`),
+	)
+	require.NoError(t, err)
+
+	actual := normalizeKeys(cfg.ComposeForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28}))
+	expected := map[string]any{
+		"SKIP_NAME_RESOLVE":  "ON",
+		"LOG_BIN":            "OFF",
+		"PERFORMANCE_SCHEMA": "ON",
+	}
+	require.Equal(t, expected, actual)
 }
 
 func TestNormalizeSizes(t *testing.T) {
@@ -208,3 +286,18 @@ func TestNormalizeNoChange(t *testing.T) {
 	require.Equal(t, "string-value", normalize("string-value"))
 	require.Equal(t, "string-valueG", normalize("string-valueG"))
 }
+
+func TestNewMySQLConfigFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	globalFile := filepath.Join(dir, "my.cnf")
+	userFile := filepath.Join(dir, ".my.cnf")
+	writeTestFile(t, globalFile, "[mysqld]\nkey1=global\nkey2=global\n")
+	writeTestFile(t, userFile, "[mysqld]\nkey1=user\n")
+
+	cfg, err := NewMySQLConfigFromFiles([]string{globalFile, userFile})
+	require.NoError(t, err)
+
+	expected := map[string]any{"key1": "user", "key2": "global"}
+	require.Equal(t, expected, cfg.ComposeForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28}))
+	require.Equal(t, []string{globalFile, userFile}, cfg.Files())
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
@@ -14,6 +15,8 @@ var keyValidator = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
 type dbConn struct {
 	conn *sql.DB
+	// applyMode is how applySetting applies changes, SET GLOBAL by default.
+	applyMode applyMode
 }
 
 func connect(dataSourceName string) (*dbConn, error) {
@@ -28,16 +31,17 @@ func (db *dbConn) close() error {
 	return db.conn.Close()
 }
 
-// Gets MySQL version from server and returns it as a rich object.
+// Gets MySQL version from server and returns it as a rich object, with the
+// flavor of the server.
 func (db *dbConn) getVersion() (MySQLVersion, error) {
-	rows, err := db.conn.Query("SELECT VERSION()")
+	rows, err := db.conn.Query("SELECT VERSION(), @@version_comment")
 	if err != nil {
 		return MySQLVersion{}, err
 	}
 	defer rows.Close()
 	rows.Next()
-	var firstResult string
-	err = rows.Scan(&firstResult)
+	var version, versionComment string
+	err = rows.Scan(&version, &versionComment)
 	if err != nil {
 		return MySQLVersion{}, err
 	}
@@ -45,18 +49,41 @@ func (db *dbConn) getVersion() (MySQLVersion, error) {
 	if err != nil {
 		return MySQLVersion{}, err
 	}
-	version, err := ParseVersion(firstResult)
+	parsed, err := ParseServerVersion(version, versionComment)
 	if err != nil {
 		return MySQLVersion{}, err
 	}
 
-	return version, nil
+	return parsed, nil
 }
 
-// Get MySQL configuration variables.
+// Get MySQL global configuration variables. Session values are not used,
+// since the connection or driver may have changed them (e.g. sql_mode).
 func (db *dbConn) getVariables() (map[string]any, error) {
+	return db.queryVariables("SHOW GLOBAL VARIABLES")
+}
+
+// Get the global value of a single MySQL configuration variable, and
+// whether the server has it.
+func (db *dbConn) getVariable(key string) (string, bool, error) {
+	variables, err := db.queryVariables("SHOW GLOBAL VARIABLES WHERE Variable_name = ?", key)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := variables[strings.ToUpper(key)].(string)
+	return value, ok, nil
+}
+
+// Get MySQL configuration variables as seen by the current session.
+// Variables with only a global scope are reported with their global value.
+func (db *dbConn) getSessionVariables() (map[string]any, error) {
+	return db.queryVariables("SHOW SESSION VARIABLES")
+}
+
+// Run a SHOW VARIABLES style query and parse the result into a map.
+func (db *dbConn) queryVariables(query string, args ...any) (map[string]any, error) {
 	//nolint:execinquery // SHOW is incorrectly failing the lint
-	rows, err := db.conn.Query("SHOW VARIABLES")
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,37 +106,261 @@ func (db *dbConn) getVariables() (map[string]any, error) {
 	return serverVariables, nil
 }
 
-// Apply a change of a setting to the MySQL server.
-func (db *dbConn) applySetting(key string, value any) error {
-	// ensure that submitted data only contains certain subset of symbols
-	if !keyValidator.MatchString(key) {
-		return fmt.Errorf("invalid key: %s", key)
+// VariableSource describes where the server got the value of a variable,
+// as reported by performance_schema.variables_info on MySQL 8.0 and later.
+type VariableSource struct {
+	// Source is one of COMPILED, GLOBAL, SERVER, EXPLICIT, EXTRA, USER,
+	// LOGIN, COMMAND_LINE, PERSISTED or DYNAMIC.
+	Source string `json:"source"`
+	// Path is the option file the value was read from, if any.
+	Path string `json:"path,omitempty"`
+	// SetTime, SetUser and SetHost record when and by whom the variable
+	// was most recently set at runtime.
+	SetTime string `json:"set_time,omitempty"`
+	SetUser string `json:"set_user,omitempty"`
+	SetHost string `json:"set_host,omitempty"`
+}
+
+// String describes the variable source, e.g. `EXPLICIT /etc/my.cnf` or
+// `DYNAMIC, set by dba_bob@localhost at 2023-01-02 03:12:00.000000`.
+func (s *VariableSource) String() string {
+	description := s.Source
+	if s.Path != "" {
+		description += " " + s.Path
 	}
-	// initially, we assume that the value is a string
-	valueStr, ok := value.(string)
-	if !ok {
-		return fmt.Errorf("invalid value type: %T", value)
+	// The set time is also recorded for values set at startup, so only
+	// show it for runtime changes, which have a user.
+	if s.SetUser != "" {
+		description += ", set by " + s.SetUser
+		if s.SetHost != "" {
+			description += "@" + s.SetHost
+		}
+		if s.SetTime != "" {
+			description += " at " + s.SetTime
+		}
+	}
+	return description
+}
+
+// Get the source of each MySQL configuration variable from
+// performance_schema.variables_info. This table exists on MySQL 8.0 and
+// later only.
+func (db *dbConn) getVariableSources() (map[string]*VariableSource, error) {
+	rows, err := db.conn.Query(
+		"SELECT VARIABLE_NAME, VARIABLE_SOURCE, VARIABLE_PATH, SET_TIME, SET_USER, SET_HOST " +
+			"FROM performance_schema.variables_info")
+	if err != nil {
+		return nil, err
 	}
-	if valueInt, err := strconv.Atoi(valueStr); err == nil {
-		// converted to an int successfully, so we treat it as an int
-		_, err := db.conn.Exec(fmt.Sprintf(`SET GLOBAL %s = ?`, key), valueInt)
+	defer rows.Close()
+
+	sources := make(map[string]*VariableSource)
+	for rows.Next() {
+		var key string
+		var source, path, setTime, setUser, setHost sql.NullString
+		err = rows.Scan(&key, &source, &path, &setTime, &setUser, &setHost)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		sources[strings.ToUpper(key)] = &VariableSource{
+			Source:  source.String,
+			Path:    path.String,
+			SetTime: setTime.String,
+			SetUser: setUser.String,
+			SetHost: setHost.String,
 		}
-	} else {
-		// treating as a string
-		_, err := db.conn.Exec(fmt.Sprintf(`SET GLOBAL %s = ?`, key), valueStr)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Apply a change of a setting to the MySQL server, typed according to the
+// metadata of the variable, if known. MySQL reports some adjustments, such
+// as a value truncated to the valid range, as warnings rather than errors,
+// so the warnings of the statement are returned too.
+func (db *dbConn) applySetting(key string, value any, metadata *VariableMetadata) ([]string, error) {
+	query, arg, err := buildSetStatement(key, value, db.applyMode, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return db.execWithWarnings(query, arg)
+}
+
+// Removes the value of a variable saved with SET PERSIST from
+// mysqld-auto.cnf, if any, leaving its runtime value unchanged.
+func (db *dbConn) resetPersisted(key string) ([]string, error) {
+	query, err := buildResetPersistStatement(key)
+	if err != nil {
+		return nil, err
+	}
+	return db.execWithWarnings(query)
+}
+
+// Runs a statement and returns its warnings.
+func (db *dbConn) execWithWarnings(query string, args ...any) ([]string, error) {
+	// SHOW WARNINGS must run on the connection that ran the statement
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return getWarnings(ctx, conn)
+}
+
+// Get the values saved with SET PERSIST in mysqld-auto.cnf, from
+// performance_schema.persisted_variables. This table exists on MySQL 8.0
+// and later only.
+func (db *dbConn) getPersistedVariables() (map[string]any, error) {
+	return db.queryVariables(
+		"SELECT VARIABLE_NAME, VARIABLE_VALUE FROM performance_schema.persisted_variables")
+}
+
+// Returns the warnings of the last statement run on the connection, e.g.
+// `Warning 1292: Truncated incorrect max_connections value: '200000'`.
+func getWarnings(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []string
+	for rows.Next() {
+		var level, message string
+		var code int
+		err = rows.Scan(&level, &code, &message)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		warnings = append(warnings, fmt.Sprintf("%s %d: %s", level, code, message))
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// Builds the SET statement that applies a setting in the given mode, with
+// a single placeholder for the value, and returns it along with the typed
+// value. String values are typed according to the metadata of the
+// variable, while int and float64 values are used as is.
+func buildSetStatement(
+	key string, value any, mode applyMode, metadata *VariableMetadata) (query string, arg any, err error) {
+	// ensure that submitted data only contains certain subset of symbols
+	if !keyValidator.MatchString(key) {
+		return "", nil, &invalidSettingError{fmt.Sprintf("invalid key: %s", key)}
+	}
+	// variables that can't be changed at runtime can still be persisted
+	// for the next restart
+	if metadata != nil && !metadata.Dynamic && mode != applyModePersistOnly {
+		return "", nil, &notDynamicError{key}
+	}
+	if metadata != nil && metadata.Scope == ScopeSession {
+		return "", nil, &invalidSettingError{fmt.Sprintf("%s has no global value", key)}
+	}
+	query = fmt.Sprintf(`SET %s %s = ?`, mode.keyword(), key)
+	switch v := value.(type) {
+	case string:
+		return query, metadata.typedValue(v), nil
+	case int, float64:
+		return query, v, nil
+	default:
+		return "", nil, &invalidSettingError{fmt.Sprintf("invalid value type: %T", value)}
+	}
+}
+
+// Returns the statement that removes the persisted value of a variable.
+// IF EXISTS makes it safe to run again, e.g. when a rollback is replayed.
+func buildResetPersistStatement(key string) (string, error) {
+	if !keyValidator.MatchString(key) {
+		return "", &invalidSettingError{fmt.Sprintf("invalid key: %s", key)}
+	}
+	return fmt.Sprintf("RESET PERSIST IF EXISTS %s", key), nil
+}
+
+// invalidSettingError is returned for a setting that fails validation, and
+// so is never sent to the server.
+type invalidSettingError struct {
+	reason string
+}
+
+func (e *invalidSettingError) Error() string {
+	return e.reason
+}
+
+// notDynamicError is returned for a variable that the metadata says can't
+// be changed at runtime. The setting is never sent to the server, which
+// would reject it as read-only.
+type notDynamicError struct {
+	key string
+}
+
+func (e *notDynamicError) Error() string {
+	return fmt.Sprintf("%s is not dynamic: it can only be set at startup, or with --apply-mode %s",
+		e.key, applyModePersistOnly)
+}
+
+// Returns the SET statement that applies a setting in the given mode as
+// plain SQL, with the value inlined as a literal of the right type.
+func formatSetStatement(key string, value any, mode applyMode, metadata *VariableMetadata) (string, error) {
+	query, arg, err := buildSetStatement(key, value, mode, metadata)
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(query, "?", formatSQLLiteral(arg), 1) + ";", nil
+}
+
+// Returns the statement that removes the persisted value of a variable as
+// plain SQL.
+func formatResetPersistStatement(key string) (string, error) {
+	query, err := buildResetPersistStatement(key)
+	if err != nil {
+		return "", err
+	}
+	return query + ";", nil
+}
+
+// Formats a value as a SQL literal. Numbers are left unquoted, while
+// strings are quoted with the special characters escaped.
+func formatSQLLiteral(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		escaper := strings.NewReplacer(
+			`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+		return "'" + escaper.Replace(fmt.Sprint(v)) + "'"
 	}
-	return nil
 }
 
 // GetVariableKeyFrom converts the key name from mysql configuration
-// format to match the MySQL server variable key format.
+// format to match the MySQL server variable key format. The `loose-` prefix
+// is dropped, since it only tells mysqld not to fail on unknown options.
 func GetVariableKeyFrom(optionName string) string {
 	normalizedKey := strings.ToUpper(optionName)
 	normalizedKey = strings.ReplaceAll(normalizedKey, "-", "_")
+	normalizedKey = strings.TrimPrefix(normalizedKey, loosePrefix)
 	return normalizedKey
 }
+
+const loosePrefix = "LOOSE_"
+
+// IsLooseOption reports whether the option name uses the `loose-` prefix.
+// Such options are usually plugin settings that mysqld ignores when the
+// plugin is not loaded.
+func IsLooseOption(optionName string) bool {
+	normalizedKey := strings.ToUpper(optionName)
+	normalizedKey = strings.ReplaceAll(normalizedKey, "-", "_")
+	return strings.HasPrefix(normalizedKey, loosePrefix)
+}
@@ -16,6 +16,15 @@ func TestConvertOptionNameToVariableKey(t *testing.T) {
 	}
 }
 
+func TestConvertLooseOptionNameToVariableKey(t *testing.T) {
+	require.Equal(t, "RPL_SEMI_SYNC_MASTER_ENABLED",
+		GetVariableKeyFrom("loose-rpl_semi_sync_master_enabled"))
+	require.Equal(t, "RPL_SEMI_SYNC_MASTER_ENABLED",
+		GetVariableKeyFrom("loose_rpl_semi_sync_master_enabled"))
+	require.True(t, IsLooseOption("loose-rpl_semi_sync_master_enabled"))
+	require.False(t, IsLooseOption("rpl_semi_sync_master_enabled"))
+}
+
 func TestApplySetting_Int_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -27,9 +36,12 @@ func TestApplySetting_Int_Success(t *testing.T) {
 	mock.ExpectExec(`SET GLOBAL MAX_CONNECTIONS = \?`).
 		WithArgs(1000).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SHOW WARNINGS`).
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
 
-	err = d.applySetting("MAX_CONNECTIONS", "1000")
+	warnings, err := d.applySetting("MAX_CONNECTIONS", "1000", nil)
 	require.NoError(t, err)
+	require.Empty(t, warnings)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -44,8 +56,155 @@ func TestApplySetting_Str_Success(t *testing.T) {
 	mock.ExpectExec(`SET GLOBAL CHARACTER_SET_SERVER = \?`).
 		WithArgs("utf8mb4").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SHOW WARNINGS`).
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+
+	_, err = d.applySetting("CHARACTER_SET_SERVER", "utf8mb4", nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplySetting_Warnings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	d := &dbConn{conn: db}
+
+	mock.ExpectExec(`SET GLOBAL MAX_CONNECTIONS = \?`).
+		WithArgs(200000).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SHOW WARNINGS`).
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+			AddRow("Warning", 1292, "Truncated incorrect max_connections value: '200000'"))
+
+	warnings, err := d.applySetting("MAX_CONNECTIONS", "200000", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Warning 1292: Truncated incorrect max_connections value: '200000'"}, warnings)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVariable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	d := &dbConn{conn: db}
+
+	mock.ExpectQuery(`SHOW GLOBAL VARIABLES WHERE Variable_name = \?`).
+		WithArgs("MAX_CONNECTIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("max_connections", "151"))
+	mock.ExpectQuery(`SHOW GLOBAL VARIABLES WHERE Variable_name = \?`).
+		WithArgs("NO_SUCH_VARIABLE").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}))
+
+	value, ok, err := d.getVariable("MAX_CONNECTIONS")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "151", value)
+
+	_, ok, err = d.getVariable("NO_SUCH_VARIABLE")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVariables_Global(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	d := &dbConn{conn: db}
+
+	mock.ExpectQuery(`SHOW GLOBAL VARIABLES`).
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("sql_mode", "STRICT_TRANS_TABLES").
+			AddRow("max_connections", "151"))
+
+	variables, err := d.getVariables()
+	require.NoError(t, err)
+	expected := map[string]any{"SQL_MODE": "STRICT_TRANS_TABLES", "MAX_CONNECTIONS": "151"}
+	require.Equal(t, expected, variables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSessionVariables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	d := &dbConn{conn: db}
+
+	mock.ExpectQuery(`SHOW SESSION VARIABLES`).
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("sql_mode", ""))
+
+	variables, err := d.getSessionVariables()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"SQL_MODE": ""}, variables)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVariableSources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	d := &dbConn{conn: db}
+
+	mock.ExpectQuery(`SELECT VARIABLE_NAME, VARIABLE_SOURCE, VARIABLE_PATH, SET_TIME, SET_USER, SET_HOST ` +
+		`FROM performance_schema.variables_info`).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"VARIABLE_NAME", "VARIABLE_SOURCE", "VARIABLE_PATH", "SET_TIME", "SET_USER", "SET_HOST",
+		}).
+			AddRow("max_connections", "DYNAMIC", "", "2023-01-02 03:12:00.000000", "dba_bob", "localhost").
+			AddRow("connect_timeout", "EXPLICIT", "/etc/my.cnf", "2023-01-01 00:00:00.000000", nil, nil).
+			AddRow("port", "COMPILED", "", nil, nil, nil))
+
+	sources, err := d.getVariableSources()
+	require.NoError(t, err)
+	require.Equal(t, "DYNAMIC, set by dba_bob@localhost at 2023-01-02 03:12:00.000000",
+		sources["MAX_CONNECTIONS"].String())
+	require.Equal(t, "EXPLICIT /etc/my.cnf", sources["CONNECT_TIMEOUT"].String())
+	require.Equal(t, "COMPILED", sources["PORT"].String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFormatSetStatement(t *testing.T) {
+	statement, err := formatSetStatement("MAX_CONNECTIONS", "1000", applyModeGlobal, nil)
+	require.NoError(t, err)
+	require.Equal(t, "SET GLOBAL MAX_CONNECTIONS = 1000;", statement)
+
+	statement, err = formatSetStatement("DATADIR", `C:\data\`, applyModeGlobal, nil)
+	require.NoError(t, err)
+	require.Equal(t, `SET GLOBAL DATADIR = 'C:\\data\\';`, statement)
+
+	statement, err = formatSetStatement("INNODB_LOG_FILE_SIZE", "1073741824", applyModePersistOnly, nil)
+	require.NoError(t, err)
+	require.Equal(t, "SET PERSIST_ONLY INNODB_LOG_FILE_SIZE = 1073741824;", statement)
+
+	_, err = formatSetStatement("MAX_CONNECTIONS; DROP TABLE users", "1", applyModeGlobal, nil)
+	require.Error(t, err)
+}
+
+func TestGetVersion(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	mock.ExpectQuery("SELECT VERSION\\(\\), @@version_comment").WillReturnRows(
+		sqlmock.NewRows([]string{"VERSION()", "@@version_comment"}).
+			AddRow("5.5.5-10.6.12-MariaDB-log", "MariaDB Server"))
 
-	err = d.applySetting("CHARACTER_SET_SERVER", "utf8mb4")
+	version, err := db.getVersion()
 	require.NoError(t, err)
+	require.Equal(t, MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}, version)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// The directory mysqld was configured with at build time (SYSCONFDIR). This
+// is the default for MySQL builds that don't install into /etc.
+var sysconfDir = "/usr/local/mysql/etc"
+
+// The global option files read by mysqld, in the order it reads them.
+var globalOptionFiles = []string{
+	"/etc/my.cnf",
+	"/etc/mysql/my.cnf",
+	filepath.Join(sysconfDir, "my.cnf"),
+}
+
+// Returns the option files to read for the given run context, in the order
+// mysqld would read them:
+//
+//   - If a config path or --defaults-file is given, only that file is read.
+//   - Otherwise, the global option files, `$MYSQL_HOME/my.cnf`, the
+//     --defaults-extra-file and `~/.my.cnf` are read, skipping default files
+//     that don't exist.
+func getOptionFiles(context *RunContext) ([]string, error) {
+	if context.configPath != "" {
+		return []string{context.configPath}, nil
+	}
+	if context.defaultsFile != "" {
+		return []string{context.defaultsFile}, nil
+	}
+	candidates := append([]string{}, globalOptionFiles...)
+	if mysqlHome := os.Getenv("MYSQL_HOME"); mysqlHome != "" {
+		candidates = append(candidates, filepath.Join(mysqlHome, "my.cnf"))
+	}
+	var optionFiles []string
+	for _, candidate := range candidates {
+		exists, err := fileExists(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			optionFiles = append(optionFiles, candidate)
+		}
+	}
+	// Unlike the default files, the extra file must exist
+	if context.defaultsExtraFile != "" {
+		optionFiles = append(optionFiles, context.defaultsExtraFile)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		userFile := filepath.Join(home, ".my.cnf")
+		exists, err := fileExists(userFile)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			optionFiles = append(optionFiles, userFile)
+		}
+	}
+	if len(optionFiles) == 0 {
+		return nil, fmt.Errorf("no option files found in the default locations")
+	}
+	return optionFiles, nil
+}
+
+// Reports whether a regular file exists at the given path.
+func fileExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
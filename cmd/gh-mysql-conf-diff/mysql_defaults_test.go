@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOptionFilesSearchOrder(t *testing.T) {
+	dir := t.TempDir()
+	globalFile := filepath.Join(dir, "etc", "my.cnf")
+	missingFile := filepath.Join(dir, "etc", "mysql", "my.cnf")
+	mysqlHomeFile := filepath.Join(dir, "mysql_home", "my.cnf")
+	extraFile := filepath.Join(dir, "extra.cnf")
+	userFile := filepath.Join(dir, "home", ".my.cnf")
+	for _, file := range []string{globalFile, mysqlHomeFile, extraFile, userFile} {
+		writeTestFile(t, file, "[mysqld]\n")
+	}
+	originalFiles := globalOptionFiles
+	globalOptionFiles = []string{globalFile, missingFile}
+	t.Cleanup(func() { globalOptionFiles = originalFiles })
+	t.Setenv("MYSQL_HOME", filepath.Dir(mysqlHomeFile))
+	t.Setenv("HOME", filepath.Dir(userFile))
+
+	files, err := getOptionFiles(&RunContext{defaultsExtraFile: extraFile})
+	require.NoError(t, err)
+	require.Equal(t, []string{globalFile, mysqlHomeFile, extraFile, userFile}, files)
+}
+
+func TestGetOptionFilesExplicit(t *testing.T) {
+	files, err := getOptionFiles(&RunContext{configPath: "my.cnf"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"my.cnf"}, files)
+
+	files, err = getOptionFiles(&RunContext{defaultsFile: "other.cnf"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"other.cnf"}, files)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The placeholder in option group patterns that is replaced by the server's
+// MAJOR.MINOR version, e.g. `mysqld-X.Y` matches `[mysqld-8.0]`.
+const groupVersionPlaceholder = "X.Y"
+
+// OptionGroups describes which option groups (sections) of the config files
+// mysqld reads.
+type OptionGroups struct {
+	// Patterns are the group names in the order mysqld lists them. A
+	// trailing `X.Y` is replaced by the server's MAJOR.MINOR version. If
+	// empty, the groups read by the flavor of the server are used.
+	Patterns []string
+	// Suffix is the --defaults-group-suffix of the server, if any. Each
+	// group is also read with the suffix appended, e.g. `[mysqld_replica1]`.
+	Suffix string
+	// VersionRanges also reads the sections for a patch version or a range
+	// of versions of the versioned groups, e.g. `[mysqld-8.0.30]` or
+	// `[mysqld >=8.0.30 <8.4]`. mysqld itself ignores these sections.
+	VersionRanges bool
+}
+
+// The option groups read by the server of each flavor. Percona Server reads
+// the same groups as MySQL.
+var flavorOptionGroups = map[Flavor][]string{
+	FlavorMySQL: {"mysqld", "server", "mysqld-X.Y"},
+	FlavorMariaDB: {
+		"mysqld", "server", "mysqld-X.Y", "mariadb", "mariadb-X.Y",
+		"mariadbd", "mariadbd-X.Y", "client-server", "galera",
+	},
+}
+
+// Returns the option groups read by a server of the given flavor.
+func defaultOptionGroups(flavor Flavor) OptionGroups {
+	patterns, ok := flavorOptionGroups[flavor]
+	if !ok {
+		patterns = flavorOptionGroups[FlavorMySQL]
+	}
+	return OptionGroups{Patterns: patterns}
+}
+
+// ForVersion returns the names of the option groups read by a server of the
+// given version. Without patterns, these are the groups read by the flavor
+// of the server. As with mysqld, the groups with the suffix appended come
+// after the plain groups.
+func (g OptionGroups) ForVersion(version MySQLVersion) []string {
+	patterns := g.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultOptionGroups(version.Flavor).Patterns
+	}
+	var groups []string
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, groupVersionPlaceholder) {
+			pattern = strings.TrimSuffix(pattern, groupVersionPlaceholder) +
+				fmt.Sprintf("%d.%d", version.Major, version.Minor)
+		}
+		groups = append(groups, pattern)
+	}
+	if g.Suffix != "" {
+		for _, group := range groups[:len(patterns)] {
+			groups = append(groups, group+g.Suffix)
+		}
+	}
+	return groups
+}
+
+// VersionRangeGroups returns the titles of the given sections that are for
+// a patch version or a range of versions of a versioned group, and that
+// match the given version. mysqld only reads the MAJOR.MINOR groups, e.g.
+// `[mysqld-8.0]`, and ignores groups it doesn't know, so these sections
+// never change how mysqld reads the file. Sections are either for a patch
+// version, e.g. `[mysqld-8.0.30]`, or a group name followed by space
+// separated bounds that must all hold, e.g. `[mysqld >=8.0.30 <8.4]`.
+func (g OptionGroups) VersionRangeGroups(sectionTitles []string, version MySQLVersion) []string {
+	if !g.VersionRanges {
+		return nil
+	}
+	patterns := g.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultOptionGroups(version.Flavor).Patterns
+	}
+	var groups []string
+	for _, title := range sectionTitles {
+		for _, pattern := range patterns {
+			base, versioned := strings.CutSuffix(pattern, "-"+groupVersionPlaceholder)
+			if versioned && isVersionRangeMatch(base, title, version) {
+				groups = append(groups, title)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// Reports whether a section title is for a patch version or a range of
+// versions of the given group, and matches the version.
+func isVersionRangeMatch(group, sectionTitle string, version MySQLVersion) bool {
+	if patch, ok := strings.CutPrefix(sectionTitle, group+"-"); ok {
+		if strings.Count(patch, ".") != 2 {
+			return false // MAJOR.MINOR groups are read by mysqld itself
+		}
+		exact, err := parseVersionBound(patch)
+		return err == nil && version.Compare(exact) == 0
+	}
+	bounds, ok := strings.CutPrefix(sectionTitle, group+" ")
+	if !ok {
+		return false
+	}
+	fields := strings.Fields(bounds)
+	for _, bound := range fields {
+		matched, err := matchVersionBound(bound, version)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return len(fields) > 0
+}
+
+// The operators of version bounds in section titles. Longer operators come
+// first, so that `>=` is not read as `>`.
+var versionBoundOperators = []string{">=", "<=", ">", "<", "="}
+
+// Reports whether the version is within a bound such as `>=8.0.30`.
+func matchVersionBound(bound string, version MySQLVersion) (bool, error) {
+	for _, operator := range versionBoundOperators {
+		limit, ok := strings.CutPrefix(bound, operator)
+		if !ok {
+			continue
+		}
+		limitVersion, err := parseVersionBound(limit)
+		if err != nil {
+			return false, err
+		}
+		comparison := version.Compare(limitVersion)
+		switch operator {
+		case ">=":
+			return version.AtLeast(limitVersion), nil
+		case "<=":
+			return comparison <= 0, nil
+		case ">":
+			return comparison > 0, nil
+		case "<":
+			return comparison < 0, nil
+		default:
+			return comparison == 0, nil
+		}
+	}
+	return false, fmt.Errorf("invalid version bound: %s", bound)
+}
+
+// Parses the version of a bound, either MAJOR.MINOR, which is the same as
+// MAJOR.MINOR.0, or MAJOR.MINOR.PATCH.
+func parseVersionBound(bound string) (MySQLVersion, error) {
+	if strings.Count(bound, ".") == 1 {
+		bound += ".0"
+	}
+	version, err := ParseVersion(bound)
+	if err != nil {
+		return MySQLVersion{}, fmt.Errorf("invalid version bound %q: %w", bound, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionGroupsForVersion(t *testing.T) {
+	version := MySQLVersion{Major: 8, Minor: 0, Patch: 28}
+
+	require.Equal(t, []string{"mysqld", "server", "mysqld-8.0"},
+		defaultOptionGroups(FlavorMySQL).ForVersion(version))
+
+	// Without patterns, the groups of the server's flavor are read
+	mariaDB := MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}
+	require.Equal(t, []string{
+		"mysqld", "server", "mysqld-10.6", "mariadb", "mariadb-10.6",
+		"mariadbd", "mariadbd-10.6", "client-server", "galera",
+	}, OptionGroups{}.ForVersion(mariaDB))
+	percona := MySQLVersion{Major: 8, Minor: 0, Patch: 34, Flavor: FlavorPercona}
+	require.Equal(t, []string{"mysqld", "server", "mysqld-8.0"}, OptionGroups{}.ForVersion(percona))
+
+	groups := OptionGroups{
+		Patterns: []string{"mysqld", "server", "mysqld-X.Y", "mariadb", "mariadb-X.Y"},
+		Suffix:   "_replica1",
+	}
+	expected := []string{
+		"mysqld", "server", "mysqld-8.0", "mariadb", "mariadb-8.0",
+		"mysqld_replica1", "server_replica1", "mysqld-8.0_replica1",
+		"mariadb_replica1", "mariadb-8.0_replica1",
+	}
+	require.Equal(t, expected, groups.ForVersion(version))
+}
+
+func TestComposeForGroupsWithSuffix(t *testing.T) {
+	cfg, err := NewMySQLConfig(
+		[]byte(`
+[server]
+key1=server
+key2=server
+
+[mysqld_replica1]
+key1=replica1
+
+[mysqld_replica2]
+key1=replica2
+`),
+	)
+	require.NoError(t, err)
+
+	groups := OptionGroups{Patterns: defaultOptionGroups(FlavorMySQL).Patterns, Suffix: "_replica1"}
+	actual := cfg.ComposeForGroups(groups.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 28}))
+	expected := map[string]any{"key1": "replica1", "key2": "server"}
+	require.Equal(t, expected, actual)
+}
+
+func TestVersionRangeGroups(t *testing.T) {
+	sectionTitles := []string{
+		"mysqld", "mysqld-8.0", "mysqld-8.0.30", "mysqld-8.0.36",
+		"mysqld >=8.0.30 <8.4", "mysqld >=8.4", "mysqld <=8.0.36", "mysqld =8.0.36",
+		"mysqld >=banana", "mysqld ", "client >=8.0", "mariadb >=8.0",
+	}
+	version := MySQLVersion{Major: 8, Minor: 0, Patch: 36}
+
+	groups := OptionGroups{VersionRanges: true}
+	require.Equal(t, []string{"mysqld-8.0.36", "mysqld >=8.0.30 <8.4", "mysqld <=8.0.36", "mysqld =8.0.36"},
+		groups.VersionRangeGroups(sectionTitles, version))
+
+	// The MariaDB groups have version ranges too
+	mariaDB := MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}
+	require.Equal(t, []string{"mysqld >=8.4", "mariadb >=8.0"},
+		groups.VersionRangeGroups(sectionTitles, mariaDB))
+
+	// The sections are opt-in
+	require.Empty(t, OptionGroups{}.VersionRangeGroups(sectionTitles, version))
+}
+
+func TestComposeWithVersionRangeGroups(t *testing.T) {
+	cfg, err := NewMySQLConfig(
+		[]byte(`
+[mysqld]
+innodb_log_file_size=48M
+
+[mysqld >=8.0.30]
+innodb_redo_log_capacity=1G
+
+[mysqld-8.0.28]
+key1=value1
+`),
+	)
+	require.NoError(t, err)
+
+	version := MySQLVersion{Major: 8, Minor: 0, Patch: 36}
+	groups := OptionGroups{VersionRanges: true}
+	actual := cfg.ComposeForGroups(append(groups.ForVersion(version),
+		groups.VersionRangeGroups(cfg.SectionTitles(), version)...))
+	expected := map[string]any{"innodb_log_file_size": "50331648", "innodb_redo_log_capacity": "1073741824"}
+	require.Equal(t, expected, actual)
+}
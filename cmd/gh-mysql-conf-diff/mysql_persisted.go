@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The name of the file in the data directory where MySQL 8.0 keeps the
+// variables saved with SET PERSIST.
+const persistedConfigFileName = "mysqld-auto.cnf"
+
+// The --persisted-config value that looks up mysqld-auto.cnf in the data
+// directory of the server.
+const persistedConfigFromDatadir = "datadir"
+
+// The section title given to options read from mysqld-auto.cnf.
+const persistedSectionTitle = "persisted"
+
+// PersistedConfig represents the variables saved with SET PERSIST in a
+// mysqld-auto.cnf file. mysqld reads these after the option files, so they
+// take precedence over my.cnf.
+type PersistedConfig struct {
+	variables []*OptionSource
+}
+
+// A persisted variable as stored in mysqld-auto.cnf.
+type persistedVariable struct {
+	Value    string `json:"Value"`
+	Metadata struct {
+		Timestamp int64  `json:"Timestamp"`
+		User      string `json:"User"`
+		Host      string `json:"Host"`
+	} `json:"Metadata"`
+}
+
+// Sections of mysqld-auto.cnf whose values are not stored in plain text.
+var encryptedPersistedSections = map[string]bool{
+	"mysql_sensitive_dynamic_variables": true,
+}
+
+// NewPersistedConfig creates a new PersistedConfig object from the given
+// config, which can be either the path to the mysqld-auto.cnf file or
+// []byte with the file contents.
+func NewPersistedConfig(config any) (*PersistedConfig, error) {
+	confContents, err := readFile(config)
+	if err != nil {
+		return nil, err
+	}
+	configPath, _ := config.(string)
+	var document struct {
+		MySQLServer map[string]json.RawMessage `json:"mysql_server"`
+	}
+	err = json.Unmarshal(confContents, &document)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid persisted config: %w", describeConfigPath(configPath), err)
+	}
+	// Version 1 of the format stores variables directly in mysql_server,
+	// while version 2 groups them into sections, e.g.
+	// mysql_static_variables.
+	variables := make(map[string]persistedVariable)
+	for name, raw := range document.MySQLServer {
+		var variable persistedVariable
+		if isPersistedVariable(raw) && json.Unmarshal(raw, &variable) == nil {
+			variables[name] = variable
+			continue
+		}
+		if encryptedPersistedSections[name] {
+			continue
+		}
+		var section map[string]persistedVariable
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return nil, fmt.Errorf("%s: invalid persisted section %s: %w",
+				describeConfigPath(configPath), name, err)
+		}
+		for sectionName, variable := range section {
+			variables[sectionName] = variable
+		}
+	}
+	// Keep the variables in a stable order
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	persisted := &PersistedConfig{}
+	for _, name := range names {
+		variable := variables[name]
+		source := &OptionSource{
+			Name:      name,
+			Value:     variable.Value,
+			File:      configPath,
+			Section:   persistedSectionTitle,
+			Persisted: true,
+			readOrder: len(persisted.variables),
+		}
+		if variable.Metadata.User != "" {
+			source.PersistedBy = variable.Metadata.User + "@" + variable.Metadata.Host
+		}
+		if variable.Metadata.Timestamp != 0 {
+			persistedAt := time.UnixMicro(variable.Metadata.Timestamp).UTC()
+			source.PersistedAt = &persistedAt
+		}
+		persisted.variables = append(persisted.variables, source)
+	}
+	return persisted, nil
+}
+
+// Reports whether the raw JSON is a single persisted variable rather than a
+// section of variables.
+func isPersistedVariable(raw json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return false
+	}
+	_, ok := fields["Value"]
+	return ok
+}
+
+// ApplyTo layers the persisted variables on top of the options composed
+// from my.cnf, the same way mysqld does at startup. The persisted sources
+// record the my.cnf definitions they override.
+func (c *PersistedConfig) ApplyTo(
+	confOptions map[string]any, confSources map[string]*OptionSource) {
+	for _, variable := range c.variables {
+		key := GetVariableKeyFrom(variable.Name)
+		source := *variable
+		if previous, ok := confSources[key]; ok {
+			delete(confOptions, previous.Name)
+			overridden := *previous
+			overridden.Overrides = nil
+			source.Overrides = append(append([]*OptionSource{}, previous.Overrides...), &overridden)
+		}
+		confOptions[source.Name] = normalize(source.Value)
+		confSources[key] = &source
+	}
+}
+
+// Returns the path of mysqld-auto.cnf in the data directory reported by the
+// server variables.
+func getPersistedConfigPath(serverVariables map[string]any) (string, error) {
+	datadir, ok := serverVariables["DATADIR"].(string)
+	if !ok || datadir == "" {
+		return "", fmt.Errorf("server did not report a datadir")
+	}
+	return filepath.Join(strings.TrimSuffix(datadir, "/"), persistedConfigFileName), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPersistedConfigVersion1(t *testing.T) {
+	data := []byte(`{
+  "Version": 1,
+  "mysql_server": {
+    "max_connections": {
+      "Value": "300",
+      "Metadata": {"Timestamp": 1672628645000000, "User": "root", "Host": "localhost"}
+    },
+    "mysql_server_static_options": {
+      "innodb_log_file_size": {
+        "Value": "1073741824",
+        "Metadata": {"Timestamp": 1672628645000000, "User": "root", "Host": "localhost"}
+      }
+    }
+  }
+}`)
+	cfg, err := NewPersistedConfig(data)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.variables, 2)
+	require.Equal(t, "innodb_log_file_size", cfg.variables[0].Name)
+	require.Equal(t, "1073741824", cfg.variables[0].Value)
+	require.Equal(t, "max_connections", cfg.variables[1].Name)
+	require.Equal(t, "300", cfg.variables[1].Value)
+	require.Equal(t, "root@localhost", cfg.variables[1].PersistedBy)
+	require.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), *cfg.variables[1].PersistedAt)
+	require.Equal(t, "<config> [persisted by root@localhost at 2023-01-02T03:04:05Z]",
+		cfg.variables[1].String())
+}
+
+func TestNewPersistedConfigVersion2(t *testing.T) {
+	data := []byte(`{
+  "Version": 2,
+  "mysql_dynamic_parse_early_variables": {},
+  "mysql_server": {
+    "mysql_dynamic_variables": {
+      "max_connections": {"Value": "300", "Metadata": {"Timestamp": 0, "User": "", "Host": ""}}
+    },
+    "mysql_static_variables": {
+      "innodb_log_file_size": {"Value": "1073741824", "Metadata": {"Timestamp": 0, "User": "", "Host": ""}}
+    },
+    "mysql_sensitive_dynamic_variables": {
+      "master_key_id": "",
+      "variables": {}
+    }
+  }
+}`)
+	cfg, err := NewPersistedConfig(data)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.variables, 2)
+	require.Equal(t, "innodb_log_file_size", cfg.variables[0].Name)
+	require.Equal(t, "max_connections", cfg.variables[1].Name)
+	require.Equal(t, "<config> [persisted]", cfg.variables[1].String())
+}
+
+func TestPersistedConfigApplyTo(t *testing.T) {
+	cfg, err := NewPersistedConfig([]byte(`{
+  "Version": 1,
+  "mysql_server": {"max_connections": {"Value": "300", "Metadata": {}}}
+}`))
+	require.NoError(t, err)
+
+	confOptions := map[string]any{"max-connections": "100", "connect_timeout": "60"}
+	confSources := map[string]*OptionSource{
+		"MAX_CONNECTIONS": {Name: "max-connections", Value: "100", File: "my.cnf", Line: 2, Section: "mysqld"},
+		"CONNECT_TIMEOUT": {Name: "connect_timeout", Value: "60", File: "my.cnf", Line: 3, Section: "mysqld"},
+	}
+	cfg.ApplyTo(confOptions, confSources)
+
+	expected := map[string]any{"max_connections": "300", "connect_timeout": "60"}
+	require.Equal(t, expected, confOptions)
+	require.True(t, confSources["MAX_CONNECTIONS"].Persisted)
+	require.Equal(t, "my.cnf:2 [mysqld]", confSources["MAX_CONNECTIONS"].Overrides[0].String())
+}
+
+func TestGetPersistedConfigPath(t *testing.T) {
+	path, err := getPersistedConfigPath(map[string]any{"DATADIR": "/var/lib/mysql/"})
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/mysql/mysqld-auto.cnf", path)
+
+	_, err = getPersistedConfigPath(map[string]any{})
+	require.Error(t, err)
+}
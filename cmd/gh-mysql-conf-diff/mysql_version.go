@@ -6,21 +6,60 @@ import (
 	"strings"
 )
 
+// Flavor is the vendor of a MySQL-compatible server.
+type Flavor string
+
+// The server flavors. The zero value is treated as MySQL.
+const (
+	FlavorMySQL   Flavor = "MySQL"
+	FlavorMariaDB Flavor = "MariaDB"
+	FlavorPercona Flavor = "Percona"
+)
+
+// String returns the name of the flavor.
+func (f Flavor) String() string {
+	if f == "" {
+		return string(FlavorMySQL)
+	}
+	return string(f)
+}
+
 // MySQLVersion is a rich object representing a MySQL version.
 type MySQLVersion struct {
-	Major int
-	Minor int
-	Patch int
+	Major  int
+	Minor  int
+	Patch  int
+	Flavor Flavor
 }
 
-// Given a version string, parse it into a MySQLVersion object.
+// String returns the version as MAJOR.MINOR.PATCH.
+func (v MySQLVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// The prefix MariaDB puts before its version number, so that replication
+// from MySQL 5.5 replicas keeps working, e.g. `5.5.5-10.6.12-MariaDB-log`.
+const mariaDBReplicationPrefix = "5.5.5-"
+
+// Given a version string, parse it into a MySQLVersion object. The flavor
+// is guessed from the version string alone, see ParseServerVersion.
 func ParseVersion(version string) (MySQLVersion, error) {
+	flavor := FlavorMySQL
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		flavor = FlavorMariaDB
+		version = strings.TrimPrefix(version, mariaDBReplicationPrefix)
+	}
 	// Version numbers can have a suffix like `5.7.34-log`, i.e. 5.7.34 with
 	// extra logging enabled. There are other possible suffixes to the
 	// version number besides -log, including -opt, -rc, -beta, etc.
 	// For the purposes of this tool, we don't care about those, so ignore.
-	if strings.Contains(version, "-") {
-		version = strings.Split(version, "-")[0]
+	// Percona Server adds its release number instead, e.g. `8.0.34-26`.
+	if number, suffix, ok := strings.Cut(version, "-"); ok {
+		version = number
+		release, _, _ := strings.Cut(suffix, "-")
+		if _, err := strconv.Atoi(release); err == nil && flavor == FlavorMySQL {
+			flavor = FlavorPercona
+		}
 	}
 	// Split the version into parts and validate the format.
 	parts := strings.Split(version, ".")
@@ -42,8 +81,54 @@ func ParseVersion(version string) (MySQLVersion, error) {
 	}
 	// Return the composed final object.
 	return MySQLVersion{
-		Major: major,
-		Minor: minor,
-		Patch: patch,
+		Major:  major,
+		Minor:  minor,
+		Patch:  patch,
+		Flavor: flavor,
 	}, nil
 }
+
+// ParseServerVersion parses the VERSION() of a server, and detects its
+// flavor from the version and @@version_comment, e.g. `MariaDB Server` or
+// `Percona Server (GPL), Release 26, Revision 0a4d6e6e`.
+func ParseServerVersion(version, versionComment string) (MySQLVersion, error) {
+	parsed, err := ParseVersion(version)
+	if err != nil {
+		return MySQLVersion{}, err
+	}
+	comment := strings.ToLower(versionComment)
+	switch {
+	case strings.Contains(comment, "mariadb"):
+		parsed.Flavor = FlavorMariaDB
+	case strings.Contains(comment, "percona"):
+		parsed.Flavor = FlavorPercona
+	}
+	return parsed, nil
+}
+
+// Compare returns -1, 0 or +1 depending on whether the version is older
+// than, the same as, or newer than the other version.
+func (v MySQLVersion) Compare(other MySQLVersion) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Reports whether the server reports where its variables were set, in
+// performance_schema.variables_info. MySQL added it in 8.0, while MariaDB
+// doesn't have it.
+func (v MySQLVersion) hasVariablesInfo() bool {
+	return v.Major >= 8 && v.Flavor != FlavorMariaDB
+}
+
+// AtLeast reports whether the version is the same as, or newer than, the
+// other version.
+func (v MySQLVersion) AtLeast(other MySQLVersion) bool {
+	return v.Compare(other) >= 0
+}
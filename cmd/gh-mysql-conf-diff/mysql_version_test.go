@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("8.0.28-log")
+	require.NoError(t, err)
+	require.Equal(t, MySQLVersion{Major: 8, Minor: 0, Patch: 28, Flavor: FlavorMySQL}, version)
+
+	_, err = ParseVersion("8.0")
+	require.Error(t, err)
+}
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		version        string
+		versionComment string
+		expected       MySQLVersion
+	}{
+		{
+			"when the server is MySQL", "8.0.36", "MySQL Community Server - GPL",
+			MySQLVersion{Major: 8, Minor: 0, Patch: 36, Flavor: FlavorMySQL},
+		},
+		{
+			"when the server is MariaDB with the replication prefix", "5.5.5-10.6.12-MariaDB-log", "MariaDB Server",
+			MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB},
+		},
+		{
+			"when the server is MariaDB without the replication prefix", "11.4.2-MariaDB", "",
+			MySQLVersion{Major: 11, Minor: 4, Patch: 2, Flavor: FlavorMariaDB},
+		},
+		{
+			"when the server is Percona", "8.0.34-26", "Percona Server (GPL), Release 26, Revision 0a4d6e6e",
+			MySQLVersion{Major: 8, Minor: 0, Patch: 34, Flavor: FlavorPercona},
+		},
+		{
+			"when the Percona release is only in the comment", "5.7.44-log", "Percona Server (GPL), Release 48",
+			MySQLVersion{Major: 5, Minor: 7, Patch: 44, Flavor: FlavorPercona},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := ParseServerVersion(tt.version, tt.versionComment)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, version)
+		})
+	}
+}
+
+func TestMySQLVersionCompare(t *testing.T) {
+	v8028 := MySQLVersion{Major: 8, Minor: 0, Patch: 28}
+	v8030 := MySQLVersion{Major: 8, Minor: 0, Patch: 30}
+	v840 := MySQLVersion{Major: 8, Minor: 4, Patch: 0}
+
+	require.Equal(t, -1, v8028.Compare(v8030))
+	require.Equal(t, 1, v840.Compare(v8030))
+	require.Equal(t, 0, v8030.Compare(v8030))
+	require.True(t, v8030.AtLeast(v8030))
+	require.True(t, v840.AtLeast(v8030))
+	require.False(t, v8028.AtLeast(v8030))
+}
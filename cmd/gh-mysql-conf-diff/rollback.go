@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// The command that replays a rollback script against a server.
+const commandRollback = "rollback"
+
+// Returns the default name of the rollback script for an apply run against
+// the given server, e.g. `rollback-localhost_3306-20240102T031200.sql`.
+func defaultRollbackFileName(serverAndPort string, now time.Time) string {
+	server := strings.NewReplacer(":", "_", "/", "_").Replace(serverAndPort)
+	return fmt.Sprintf("rollback-%s-%s.sql", server, now.Format("20060102T150405"))
+}
+
+// Reports whether the result is a change to restore: one that was
+// applied, or a difference that is about to be applied.
+func needsRollback(result *OptionResult) bool {
+	switch result.Status {
+	case StatusDifferent, StatusApplied, StatusAdjusted, StatusStaged:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reports whether any change in the report was applied or is about to be,
+// and so needs a rollback script.
+func hasRollbackChanges(report *DiffReport) bool {
+	for _, result := range report.Options {
+		if needsRollback(result) {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes a SQL script that restores the server values the changes in the
+// report replaced. Before applying, it covers every difference about to be
+// applied, and afterwards, the changes that were applied. The statements
+// are in the reverse order of the changes, so that the server goes back
+// through the same states (see rollbackStatements). A difference that
+// fails validation is never applied, so it is left as a comment, like in
+// the SQL script of --emit-sql.
+func writeRollbackScript(report *DiffReport, now time.Time, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "-- Rollback generated by %s for %s (%s %s) at %s\n",
+		getBinaryName(), report.Server, report.Flavor, report.Version, now.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	for i := len(report.Options) - 1; i >= 0; i-- {
+		result := report.Options[i]
+		if !needsRollback(result) {
+			continue
+		}
+		applyValue, rollbackValue := result.changeValues()
+		_, err = fmt.Fprintf(w, "\n%s\n", formatSQLComment(
+			fmt.Sprintf("%s: restores %s, was set to %s", result.Key, rollbackValue, applyValue)))
+		if err != nil {
+			return err
+		}
+		statements, err := rollbackStatements(result, report.ApplyMode)
+		if err == nil {
+			_, err = formatSetStatement(result.Key, applyValue, report.ApplyMode, result.Metadata)
+		}
+		if err != nil {
+			statements = []string{formatSQLComment(fmt.Sprintf("Skipped: %v", err))}
+		}
+		for _, statement := range statements {
+			_, err = fmt.Fprintf(w, "%s\n", statement)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the statements that undo a change applied in the given mode.
+// The runtime value is restored with SET GLOBAL. SET PERSIST and
+// SET PERSIST_ONLY also replaced the value in mysqld-auto.cnf, so the value
+// persisted before is persisted again, or removed with RESET PERSIST if
+// there was none.
+func rollbackStatements(result *OptionResult, mode applyMode) ([]string, error) {
+	_, rollbackValue := result.changeValues()
+	var statements []string
+	if mode == applyModePersist || mode == applyModePersistOnly {
+		var statement string
+		var err error
+		if result.PersistedValue != nil {
+			statement, err = formatSetStatement(
+				result.Key, *result.PersistedValue, applyModePersistOnly, result.Metadata)
+		} else {
+			statement, err = formatResetPersistStatement(result.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	// SET PERSIST_ONLY left the runtime value unchanged
+	if mode != applyModePersistOnly {
+		statement, err := formatSetStatement(result.Key, rollbackValue, applyModeGlobal, result.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// Applies the differences in the report to the server, saving the rollback
+// script to the given path before changing anything, so that the changes
+// can be restored even if the run is interrupted. Once applied, the script
+// is saved again with the changes that were actually made.
+func applyWithRollback(db *dbConn, report *DiffReport, now time.Time, path string) error {
+	// SET PERSIST replaces any value persisted before, which the rollback
+	// restores
+	if report.ApplyMode != applyModeGlobal {
+		persistedVariables, err := db.getPersistedVariables()
+		if err != nil {
+			return fmt.Errorf("failed to query MySQL for persisted variables, no changes were applied: %w", err)
+		}
+		addPersistedValues(report.Options, persistedVariables)
+	}
+	err := saveRollbackScript(report, now, path)
+	if err != nil {
+		return fmt.Errorf("failed to write rollback script, no changes were applied: %w", err)
+	}
+	applyChanges(db, report.Options)
+	err = saveRollbackScript(report, now, path)
+	if err != nil {
+		return fmt.Errorf("failed to update rollback script %s, "+
+			"it restores all the changes that were attempted: %w", path, err)
+	}
+	return nil
+}
+
+// Writes the rollback script for the report to the given path.
+func saveRollbackScript(report *DiffReport, now time.Time, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeRollbackScript(report, now, file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Reads the rollback script at the given path and replays its statements
+// against the server in order, through the same validation as
+// applySetting, with the metadata for the version of the server. Every
+// statement is attempted, even if an earlier one failed, and the exit code
+// reflects whether any failed.
+func rollback(db *dbConn, path string, stdout, stderr io.Writer) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return exitError, err
+	}
+	defer file.Close()
+	settings, err := parseSQLScript(file)
+	if err != nil {
+		return exitError, fmt.Errorf("%s: %w", path, err)
+	}
+	version, err := db.getVersion()
+	if err != nil {
+		return exitError, fmt.Errorf("failed to read mysql version: %w", err)
+	}
+	catalog := systemVariables.ForVersion(version)
+	exitCode := exitInSync
+	for _, setting := range settings {
+		var warnings []string
+		if setting.Reset {
+			warnings, err = db.resetPersisted(setting.Key)
+		} else {
+			// Each statement is replayed in the mode it was written in
+			db.applyMode = setting.Mode
+			warnings, err = db.applySetting(setting.Key, setting.Value, catalog[setting.Key])
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: Failed to %s variable %s (%s:%d): %v\n",
+				setting.statementName(), setting.Key, path, setting.Line, err)
+			exitCode = exitApplyFailed
+			continue
+		}
+		if setting.Reset {
+			_, _ = fmt.Fprintf(stdout, "Reset persisted variable:\n  %s\n", setting.Key)
+		} else {
+			_, _ = fmt.Fprintf(stdout, "Set variable:\n  %s = %v\n", setting.Key, setting.Value)
+		}
+		for _, warning := range warnings {
+			_, _ = fmt.Fprintf(stderr, "Warning: %s variable %s: %s\n",
+				setting.statementName(), setting.Key, warning)
+		}
+	}
+	return exitCode, nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRollbackFileName(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 12, 0, 0, time.UTC)
+	require.Equal(t, "rollback-localhost_3306-20240102T031200.sql",
+		defaultRollbackFileName("localhost:3306", now))
+}
+
+func TestWriteRollbackScript(t *testing.T) {
+	report := &DiffReport{
+		Server:  "localhost:3306",
+		Version: "8.0.28",
+		Options: []*OptionResult{
+			{Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusApplied},
+			{Key: "INIT_CONNECT", ConfigValue: "SET NAMES utf8mb4", ServerValue: "", Status: StatusApplied},
+			{Key: "MAX_CONNECTIONS", ConfigValue: "1000", ServerValue: "151", Status: StatusFailed},
+			{Key: "SQL_MODE", ConfigValue: "", ServerValue: "STRICT_TRANS_TABLES", Status: StatusApplied},
+		},
+	}
+	now := time.Date(2024, 1, 2, 3, 12, 0, 0, time.UTC)
+
+	script := bytes.Buffer{}
+	err := writeRollbackScript(report, now, &script)
+	require.NoError(t, err)
+
+	// The applied changes are undone in reverse order
+	expected := "-- Rollback generated by " + getBinaryName() +
+		" for localhost:3306 (MySQL 8.0.28) at 2024-01-02T03:12:00Z\n" +
+		"\n" +
+		"-- SQL_MODE: restores STRICT_TRANS_TABLES, was set to \n" +
+		"SET GLOBAL SQL_MODE = 'STRICT_TRANS_TABLES';\n" +
+		"\n" +
+		"-- INIT_CONNECT: restores , was set to SET NAMES utf8mb4\n" +
+		"SET GLOBAL INIT_CONNECT = '';\n" +
+		"\n" +
+		"-- CONNECT_TIMEOUT: restores 30, was set to 60\n" +
+		"SET GLOBAL CONNECT_TIMEOUT = 30;\n"
+	require.Equal(t, expected, script.String())
+}
+
+func TestRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollback.sql")
+	writeTestFile(t, path, "-- Rollback generated by gh-mysql-conf-diff\n"+
+		"\n"+
+		"-- SQL_MODE: restores STRICT_TRANS_TABLES, was set to \n"+
+		"SET GLOBAL SQL_MODE = 'STRICT_TRANS_TABLES';\n"+
+		"\n"+
+		"-- CONNECT_TIMEOUT: restores 30, was set to 60\n"+
+		"SET GLOBAL CONNECT_TIMEOUT = 30;\n"+
+		"\n"+
+		"-- INNODB_LOG_FILE_SIZE: restores 50331648, was set to 100663296\n"+
+		"SET GLOBAL INNODB_LOG_FILE_SIZE = 50331648;\n")
+
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+	m.ExpectQuery("SELECT VERSION\\(\\), @@version_comment").WillReturnRows(
+		sqlmock.NewRows([]string{"VERSION()", "@@version_comment"}).AddRow("8.0.36", "MySQL Community Server - GPL"))
+	m.ExpectExec("SET GLOBAL SQL_MODE = ?").WithArgs("STRICT_TRANS_TABLES").
+		WillReturnError(errors.New("access denied"))
+	m.ExpectExec("SET GLOBAL CONNECT_TIMEOUT = ?").WithArgs(30).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	exitCode, err := rollback(db, path, &stdout, &stderr)
+	require.NoError(t, err)
+
+	// Every statement is attempted, even after a failure
+	require.Equal(t, exitApplyFailed, exitCode)
+	require.Equal(t, "Set variable:\n  CONNECT_TIMEOUT = 30\n", stdout.String())
+	// The metadata of the variables is checked, as when applying
+	require.Equal(t, "Warning: Failed to SET variable SQL_MODE ("+path+":4): access denied\n"+
+		"Warning: Failed to SET variable INNODB_LOG_FILE_SIZE ("+path+":10): INNODB_LOG_FILE_SIZE is not dynamic: "+
+		"it can only be set at startup, or with --apply-mode persist-only\n", stderr.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestRollbackRejectsOtherSQL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollback.sql")
+	writeTestFile(t, path, "SET GLOBAL CONNECT_TIMEOUT = 30;\nDROP TABLE users;\n")
+
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	// Nothing is applied if any statement is invalid
+	_, err = rollback(db, path, &bytes.Buffer{}, &bytes.Buffer{})
+	require.EqualError(t, err, path+": line 2: not a SET statement: DROP TABLE users;")
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestWriteRollbackScriptBeforeApplying(t *testing.T) {
+	report := &DiffReport{
+		Server:  "localhost:3306",
+		Version: "8.0.28",
+		Options: []*OptionResult{
+			{Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusDifferent},
+			{Key: "MAX_CONNECTIONS", ConfigValue: "151", ServerValue: "151", Status: StatusEqual},
+		},
+	}
+	require.True(t, hasRollbackChanges(report))
+
+	// The differences about to be applied are covered
+	script := bytes.Buffer{}
+	require.NoError(t, writeRollbackScript(report, time.Now(), &script))
+	require.Contains(t, script.String(), "SET GLOBAL CONNECT_TIMEOUT = 30;\n")
+	require.NotContains(t, script.String(), "MAX_CONNECTIONS")
+
+	// Changes that failed are not
+	report.Options[0].Status = StatusFailed
+	require.False(t, hasRollbackChanges(report))
+}
+
+func TestWriteRollbackScriptPersisted(t *testing.T) {
+	persisted := "100"
+	report := &DiffReport{
+		Server:    "localhost:3306",
+		Version:   "8.0.28",
+		ApplyMode: applyModePersist,
+		Options: []*OptionResult{
+			{Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusApplied},
+			{Key: "MAX_CONNECTIONS", ConfigValue: "1000", ServerValue: "151", Status: StatusApplied,
+				PersistedValue: &persisted},
+		},
+	}
+
+	// The persisted value is restored, or removed if there was none, and
+	// the runtime value is restored with SET GLOBAL
+	script := bytes.Buffer{}
+	require.NoError(t, writeRollbackScript(report, time.Now(), &script))
+	require.Contains(t, script.String(),
+		"SET PERSIST_ONLY MAX_CONNECTIONS = 100;\nSET GLOBAL MAX_CONNECTIONS = 151;\n")
+	require.Contains(t, script.String(),
+		"RESET PERSIST IF EXISTS CONNECT_TIMEOUT;\nSET GLOBAL CONNECT_TIMEOUT = 30;\n")
+
+	// SET PERSIST_ONLY left the runtime value unchanged
+	report.ApplyMode = applyModePersistOnly
+	script.Reset()
+	require.NoError(t, writeRollbackScript(report, time.Now(), &script))
+	require.Contains(t, script.String(), "RESET PERSIST IF EXISTS CONNECT_TIMEOUT;\n")
+	require.NotContains(t, script.String(), "SET GLOBAL")
+}
+
+func TestRollbackResetPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollback.sql")
+	writeTestFile(t, path, "RESET PERSIST IF EXISTS CONNECT_TIMEOUT;\nSET GLOBAL CONNECT_TIMEOUT = 30;\n")
+
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+	m.ExpectQuery("SELECT VERSION\\(\\), @@version_comment").WillReturnRows(
+		sqlmock.NewRows([]string{"VERSION()", "@@version_comment"}).AddRow("8.0.36", "MySQL Community Server - GPL"))
+	m.ExpectExec("RESET PERSIST IF EXISTS CONNECT_TIMEOUT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	m.ExpectExec("SET GLOBAL CONNECT_TIMEOUT = ?").WithArgs(30).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").
+		WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+
+	stdout := bytes.Buffer{}
+	exitCode, err := rollback(db, path, &stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, exitInSync, exitCode)
+	require.Equal(t, "Reset persisted variable:\n  CONNECT_TIMEOUT\n"+
+		"Set variable:\n  CONNECT_TIMEOUT = 30\n", stdout.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
+
+func TestApplyWithRollbackSkipsInvalidChanges(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	report := &DiffReport{
+		Server:    "localhost:3306",
+		Version:   "8.0.36",
+		ApplyMode: applyModeGlobal,
+		Options: []*OptionResult{
+			{Key: "INNODB_LOG_FILE_SIZE", ConfigValue: "100663296", ServerValue: "50331648",
+				Status: StatusDifferent, Metadata: catalog["INNODB_LOG_FILE_SIZE"]},
+			{Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30",
+				Status: StatusDifferent, Metadata: catalog["CONNECT_TIMEOUT"]},
+		},
+	}
+
+	// A difference that can't be applied is left as a comment
+	script := bytes.Buffer{}
+	require.NoError(t, writeRollbackScript(report, time.Now(), &script))
+	require.Contains(t, script.String(), "-- Skipped: INNODB_LOG_FILE_SIZE is not dynamic")
+	require.Contains(t, script.String(), "SET GLOBAL CONNECT_TIMEOUT = 30;\n")
+
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+	noWarnings := sqlmock.NewRows([]string{"Level", "Code", "Message"})
+	m.ExpectExec("SET GLOBAL CONNECT_TIMEOUT = ?").WithArgs(60).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(noWarnings)
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WithArgs("CONNECT_TIMEOUT").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("connect_timeout", "60"))
+
+	// The other differences are still applied
+	path := filepath.Join(t.TempDir(), "rollback.sql")
+	require.NoError(t, applyWithRollback(db, report, time.Now(), path))
+	require.Equal(t, StatusReadOnly, report.Options[0].Status)
+	require.Equal(t, StatusApplied, report.Options[1].Status)
+	require.NoError(t, m.ExpectationsWereMet())
+
+	// And rolled back
+	m.ExpectQuery("SELECT VERSION\\(\\), @@version_comment").WillReturnRows(
+		sqlmock.NewRows([]string{"VERSION()", "@@version_comment"}).AddRow("8.0.36", "MySQL Community Server - GPL"))
+	m.ExpectExec("SET GLOBAL CONNECT_TIMEOUT = ?").WithArgs(30).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery("SHOW WARNINGS").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	stdout := bytes.Buffer{}
+	exitCode, err := rollback(db, path, &stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, exitInSync, exitCode)
+	require.Equal(t, "Set variable:\n  CONNECT_TIMEOUT = 30\n", stdout.String())
+	require.NoError(t, m.ExpectationsWereMet())
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The command that compares the global variables of two servers.
+const commandDiffServers = "diff-servers"
+
+// The variables that are expected to differ between servers, because they
+// identify the host, hold the state of the instance, or are paths that
+// contain its hostname by default. These are not compared by diff-servers.
+// --skip-variables adds to them, and any other path that contains the
+// hostname of the server is skipped too (see isHostPath).
+var perHostVariables = []string{
+	"server_id",
+	"server_uuid",
+	"hostname",
+	"report_host",
+	"report_port",
+	"gtid_executed",
+	"gtid_purged",
+	"gtid_owned",
+	"group_replication_local_address",
+	"pid_file",
+	"log_error",
+	"general_log_file",
+	"slow_query_log_file",
+	"log_bin_basename",
+	"log_bin_index",
+	"relay_log",
+	"relay_log_basename",
+	"relay_log_index",
+	"relay_log_info_file",
+}
+
+// ServerVariables is the version and global variables of a server, read
+// from the server itself or from a snapshot of it.
+type ServerVariables struct {
+	Server    string
+	Version   MySQLVersion
+	Variables map[string]any
+}
+
+// Reads the version and global variables of a server.
+func readServerVariables(server string, source variableSource) (*ServerVariables, error) {
+	version, err := source.getVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mysql version of %s: %w", server, err)
+	}
+	variables, err := source.getVariables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s for server variables: %w", server, err)
+	}
+	return &ServerVariables{Server: server, Version: version, Variables: variables}, nil
+}
+
+// VariableResult is the outcome of comparing a variable between two
+// servers. Status is StatusEqual, StatusDifferent, or StatusMissing when
+// only one of the servers has the variable.
+type VariableResult struct {
+	Key    string     `json:"key"`
+	Status DiffStatus `json:"status"`
+	// Values are nil for a server that doesn't have the variable.
+	Value      *string `json:"value"`
+	OtherValue *string `json:"other_value"`
+}
+
+// ServerDiffReport is the result of comparing two servers.
+type ServerDiffReport struct {
+	Server       string            `json:"server"`
+	Version      string            `json:"version"`
+	Flavor       Flavor            `json:"flavor,omitempty"`
+	OtherServer  string            `json:"other_server"`
+	OtherVersion string            `json:"other_version"`
+	OtherFlavor  Flavor            `json:"other_flavor,omitempty"`
+	Notes        []string          `json:"notes,omitempty"`
+	Skipped      []string          `json:"skipped"`
+	Variables    []*VariableResult `json:"variables"`
+}
+
+// Compares the global variables of two servers, with the same comparators
+// as mysqlConfDiff, using the metadata for the version of the first server.
+// The variables to skip are keyed by server variable key. If any variables
+// are watched, only those are compared.
+func diffServers(
+	server, other *ServerVariables,
+	catalog VariableCatalog,
+	variablesToSkip map[string]any,
+	variablesToWatch map[string]any,
+) *ServerDiffReport {
+	report := &ServerDiffReport{
+		Server:       server.Server,
+		Version:      server.Version.String(),
+		Flavor:       server.Version.Flavor,
+		OtherServer:  other.Server,
+		OtherVersion: other.Version.String(),
+		OtherFlavor:  other.Version.Flavor,
+		Notes:        metadataNotes(server.Version),
+		Skipped:      []string{},
+		Variables:    []*VariableResult{},
+	}
+	keys := make(map[string]bool)
+	for key := range server.Variables {
+		keys[key] = true
+	}
+	for key := range other.Variables {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		if len(variablesToWatch) > 0 && variablesToWatch[key] == nil {
+			continue
+		}
+		if variablesToSkip[key] != nil ||
+			isHostPath(catalog[key], server, key) || isHostPath(catalog[key], other, key) {
+			report.Skipped = append(report.Skipped, key)
+			continue
+		}
+		result := &VariableResult{Key: key}
+		report.Variables = append(report.Variables, result)
+		value, ok := server.Variables[key]
+		if ok {
+			result.Value = stringPointer(fmt.Sprint(value))
+		}
+		otherValue, otherOk := other.Variables[key]
+		if otherOk {
+			result.OtherValue = stringPointer(fmt.Sprint(otherValue))
+		}
+		switch {
+		case !ok || !otherOk:
+			result.Status = StatusMissing
+		case isEqualServerValues(catalog[key], *result.Value, *result.OtherValue):
+			result.Status = StatusEqual
+		default:
+			result.Status = StatusDifferent
+		}
+	}
+	return report
+}
+
+// Reports whether the variable is a path that contains the hostname of the
+// server, e.g. a datadir of /data/db1, which differs between servers by
+// design.
+func isHostPath(metadata *VariableMetadata, server *ServerVariables, key string) bool {
+	if metadata == nil || metadata.Type != TypePath {
+		return false
+	}
+	hostname, _ := server.Variables["HOSTNAME"].(string)
+	value, _ := server.Variables[key].(string)
+	return hostname != "" && strings.Contains(value, hostname)
+}
+
+// Reports whether the values of a variable on two servers are equal. Flag
+// lists are compared in both directions, since the comparator only checks
+// the flags set in its first value, as a config sets a few flags only.
+func isEqualServerValues(metadata *VariableMetadata, value, otherValue string) bool {
+	if metadata != nil && metadata.Type == TypeFlags {
+		return isEqualFlags(value, otherValue) && isEqualFlags(otherValue, value)
+	}
+	return compareValues(metadata, value, otherValue)
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+// Returns the variables to skip, the per-host variables plus the given
+// ones, keyed by server variable key.
+func variablesToSkip(extra []string) map[string]any {
+	skip := make(map[string]any)
+	for _, name := range append(append([]string{}, perHostVariables...), extra...) {
+		skip[GetVariableKeyFrom(name)] = true
+	}
+	return skip
+}
+
+// ExitCode returns the exit code of the program for the report: drift if
+// any variable differs or is on one server only.
+func (r *ServerDiffReport) ExitCode() int {
+	for _, result := range r.Variables {
+		if result.Status != StatusEqual {
+			return exitDrift
+		}
+	}
+	return exitInSync
+}
+
+// Writes the report in the given format.
+func writeServerDiffReport(report *ServerDiffReport, format string, stdout, stderr io.Writer) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case formatText:
+		writeTextServerDiffReport(report, stdout, stderr)
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// Writes the report as human readable text. Differences are printed to
+// stdout, while the servers compared and skipped variables are printed to
+// stderr.
+func writeTextServerDiffReport(report *ServerDiffReport, stdout, stderr io.Writer) {
+	_, _ = fmt.Fprintf(stderr, "Comparing %s (%s %s) with %s (%s %s)\n",
+		report.Server, report.Flavor, report.Version, report.OtherServer, report.OtherFlavor, report.OtherVersion)
+	for _, note := range report.Notes {
+		_, _ = fmt.Fprintf(stderr, "Note: %s\n", note)
+	}
+	if len(report.Skipped) > 0 {
+		_, _ = fmt.Fprintf(stderr, "Skipped per-host variables:\n")
+		for _, key := range report.Skipped {
+			_, _ = fmt.Fprintf(stderr, "  %s\n", key)
+		}
+	}
+	for _, result := range report.Variables {
+		switch result.Status {
+		case StatusEqual:
+			continue // Nothing to report
+		case StatusMissing:
+			server, value := report.Server, result.Value
+			if value == nil {
+				server, value = report.OtherServer, result.OtherValue
+			}
+			_, _ = fmt.Fprintf(stdout, "Only on %s: %s\n  %s\n", server, result.Key, *value)
+		default:
+			_, _ = fmt.Fprintf(stdout, "Difference found for: %s\n", result.Key)
+			_, _ = fmt.Fprintf(stdout, "  %s: %s\n", report.Server, *result.Value)
+			_, _ = fmt.Fprintf(stdout, "  %s: %s\n", report.OtherServer, *result.OtherValue)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffServers(t *testing.T) {
+	version := MySQLVersion{Major: 8, Minor: 0, Patch: 36}
+	primary := &ServerVariables{
+		Server:  "db1:3306",
+		Version: version,
+		Variables: map[string]any{
+			"SERVER_ID":          "1",
+			"HOSTNAME":           "db1",
+			"GTID_EXECUTED":      "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5",
+			"DATADIR":            "/data/db1/",
+			"TMPDIR":             "/tmp",
+			"PID_FILE":           "/var/run/mysqld/db1.pid",
+			"SLOW_QUERY_LOG":     "ON",
+			"MAX_CONNECTIONS":    "500",
+			"LONG_QUERY_TIME":    "2.000000",
+			"SYNC_BINLOG":        "1",
+			"RPL_SEMI_SYNC_MODE": "AFTER_SYNC",
+			"OPTIMIZER_SWITCH":   "index_merge=on,mrr=on",
+			"OPTIMIZER_TRACE":    "enabled=off,one_line=off",
+		},
+	}
+	replica := &ServerVariables{
+		Server:  "db2:3306",
+		Version: version,
+		Variables: map[string]any{
+			"SERVER_ID":       "2",
+			"HOSTNAME":        "db2",
+			"GTID_EXECUTED":   "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4a9a1a24-71ca-11e1-9e33-c80aa9429562:1",
+			"DATADIR":         "/data/db2/",
+			"TMPDIR":          "/tmp",
+			"PID_FILE":        "/var/run/mysqld/db2.pid",
+			"SLOW_QUERY_LOG":  "1",
+			"MAX_CONNECTIONS": "151",
+			"LONG_QUERY_TIME": "2",
+			"SYNC_BINLOG":     "0",
+			// A flag the other server doesn't have is a difference
+			"OPTIMIZER_SWITCH": "index_merge=on,mrr=on,hash_join=on",
+			"OPTIMIZER_TRACE":  "ONE_LINE=off,enabled=off",
+		},
+	}
+
+	report := diffServers(primary, replica, systemVariables.ForVersion(version),
+		variablesToSkip([]string{"sync-binlog"}), nil)
+
+	// Paths that contain the hostname are skipped, as well as the per-host
+	// variables
+	require.Equal(t, []string{"DATADIR", "GTID_EXECUTED", "HOSTNAME", "PID_FILE", "SERVER_ID", "SYNC_BINLOG"},
+		report.Skipped)
+	statuses := make(map[string]DiffStatus)
+	for _, result := range report.Variables {
+		statuses[result.Key] = result.Status
+	}
+	require.Equal(t, map[string]DiffStatus{
+		"LONG_QUERY_TIME":    StatusEqual,
+		"MAX_CONNECTIONS":    StatusDifferent,
+		"OPTIMIZER_SWITCH":   StatusDifferent,
+		"OPTIMIZER_TRACE":    StatusEqual,
+		"RPL_SEMI_SYNC_MODE": StatusMissing,
+		"SLOW_QUERY_LOG":     StatusEqual,
+		"TMPDIR":             StatusEqual,
+	}, statuses)
+	require.Equal(t, exitDrift, report.ExitCode())
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	require.NoError(t, writeServerDiffReport(report, formatText, &stdout, &stderr))
+	assert.Equal(t, "Difference found for: MAX_CONNECTIONS\n"+
+		"  db1:3306: 500\n"+
+		"  db2:3306: 151\n"+
+		"Difference found for: OPTIMIZER_SWITCH\n"+
+		"  db1:3306: index_merge=on,mrr=on\n"+
+		"  db2:3306: index_merge=on,mrr=on,hash_join=on\n"+
+		"Only on db1:3306: RPL_SEMI_SYNC_MODE\n"+
+		"  AFTER_SYNC\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Comparing db1:3306 (MySQL 8.0.36) with db2:3306 (MySQL 8.0.36)\n")
+
+	// Watched variables limit the comparison
+	report = diffServers(primary, replica, systemVariables.ForVersion(version),
+		variablesToSkip(nil), map[string]any{"LONG_QUERY_TIME": true})
+	require.Len(t, report.Variables, 1)
+	require.Equal(t, exitInSync, report.ExitCode())
+}
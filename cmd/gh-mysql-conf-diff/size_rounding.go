@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// sizeRounding is how the server rounds a numeric variable: to a multiple
+// of a block size, either up or down.
+type sizeRounding struct {
+	multiple uint64
+	up       bool
+}
+
+// The rounding of variables whose multiple depends on other server
+// variables, keyed by the server variable key. These take precedence over
+// the block size in the variable metadata.
+var variableRoundings = map[string]func(serverVariables map[string]any) (sizeRounding, bool){
+	"INNODB_BUFFER_POOL_SIZE": bufferPoolRounding,
+}
+
+// innodb_buffer_pool_size is rounded up to a multiple of
+// innodb_buffer_pool_chunk_size * innodb_buffer_pool_instances.
+func bufferPoolRounding(serverVariables map[string]any) (sizeRounding, bool) {
+	chunkSize, chunkSizeOk := serverVariableUint(serverVariables, "INNODB_BUFFER_POOL_CHUNK_SIZE")
+	instances, instancesOk := serverVariableUint(serverVariables, "INNODB_BUFFER_POOL_INSTANCES")
+	if !chunkSizeOk || !instancesOk || chunkSize == 0 || instances == 0 {
+		return sizeRounding{}, false
+	}
+	return sizeRounding{multiple: chunkSize * instances, up: true}, true
+}
+
+// Returns the value of a numeric server variable, and whether it has one.
+func serverVariableUint(serverVariables map[string]any, key string) (uint64, bool) {
+	value, ok := serverVariables[key].(string)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	return number, err == nil
+}
+
+// Returns the rounding the server applies to a variable, and whether it
+// rounds it at all.
+func roundingFor(metadata *VariableMetadata, serverVariables map[string]any) (sizeRounding, bool) {
+	if metadata == nil || (metadata.Type != TypeInt && metadata.Type != TypeSize) {
+		return sizeRounding{}, false
+	}
+	if rounding, ok := variableRoundings[GetVariableKeyFrom(metadata.Name)]; ok {
+		return rounding(serverVariables)
+	}
+	if metadata.BlockSize > 1 {
+		return sizeRounding{multiple: metadata.BlockSize}, true
+	}
+	return sizeRounding{}, false
+}
+
+// How the server adjusts a config value, for the note on it.
+const (
+	adjustmentRounded = "rounded"
+	adjustmentClamped = "clamped"
+)
+
+// Returns the value the server would set a variable to for a config value,
+// after rounding it to the multiple of the variable and clamping it to the
+// range of the variable, and how it was adjusted, if at all. Clamping takes
+// precedence in the adjustment, since it changes the value the most.
+func roundValue(metadata *VariableMetadata, value string, serverVariables map[string]any) (string, string) {
+	if metadata == nil || (metadata.Type != TypeInt && metadata.Type != TypeSize) {
+		return value, ""
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return value, ""
+	}
+	rounded := number
+	adjustment := ""
+	if rounding, ok := roundingFor(metadata, serverVariables); ok {
+		remainder := rounded % rounding.multiple
+		if remainder != 0 {
+			rounded -= remainder
+			if rounding.up {
+				rounded += rounding.multiple
+			}
+			adjustment = adjustmentRounded
+		}
+	}
+	switch {
+	case metadata.Min != nil && float64(rounded) < *metadata.Min:
+		rounded = uint64(*metadata.Min)
+		adjustment = adjustmentClamped
+	case metadata.Max != nil && float64(rounded) > *metadata.Max:
+		rounded = uint64(*metadata.Max)
+		adjustment = adjustmentClamped
+	}
+	return strconv.FormatUint(rounded, 10), adjustment
+}
+
+// Reports whether a config value is equal to the server value once the
+// server has rounded or clamped it, and if so returns a note on it.
+func isEqualAfterRounding(
+	metadata *VariableMetadata, optionValue, serverValue string, serverVariables map[string]any,
+) (string, bool) {
+	rounded, adjustment := roundValue(metadata, optionValue, serverVariables)
+	if adjustment == "" || !compareValues(metadata, rounded, serverValue) {
+		return "", false
+	}
+	return fmt.Sprintf("value %s is %s by the server to %s", optionValue, adjustment, rounded), true
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundValue(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	serverVariables := map[string]any{
+		"INNODB_BUFFER_POOL_CHUNK_SIZE": "134217728",
+		"INNODB_BUFFER_POOL_INSTANCES":  "8",
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		value      string
+		expected   string
+		adjustment string
+	}{
+		{"when the buffer pool size is a multiple", "INNODB_BUFFER_POOL_SIZE", "2147483648", "2147483648", ""},
+		{
+			"when the buffer pool size is rounded up", "INNODB_BUFFER_POOL_SIZE", "1500000000", "2147483648",
+			adjustmentRounded,
+		},
+		{"when the log file size is rounded down", "INNODB_LOG_FILE_SIZE", "52428900", "52428800", adjustmentRounded},
+		{"when the join buffer size is rounded down", "JOIN_BUFFER_SIZE", "262200", "262144", adjustmentRounded},
+		{"when the sort buffer size is below the minimum", "SORT_BUFFER_SIZE", "1024", "32768", adjustmentClamped},
+		// Any number of bytes is a multiple of the block size of 1
+		{"when the sort buffer size is not a multiple of a page", "SORT_BUFFER_SIZE", "262145", "262145", ""},
+		{"when the log file size is rounded below the minimum", "INNODB_LOG_FILE_SIZE", "2097152", "4194304",
+			adjustmentClamped},
+		{"when the value is above the maximum", "MAX_CONNECTIONS", "200000", "100000", adjustmentClamped},
+		{"when the value is not a number", "JOIN_BUFFER_SIZE", "large", "large", ""},
+		{"when the variable is not a size", "BINLOG_FORMAT", "ROW", "ROW", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, adjustment := roundValue(catalog[tt.key], tt.value, serverVariables)
+			require.Equal(t, tt.expected, value)
+			require.Equal(t, tt.adjustment, adjustment)
+		})
+	}
+	require.Equal(t, uint64(1), catalog["SORT_BUFFER_SIZE"].BlockSize)
+}
+
+func TestRoundValue_BufferPoolWithoutChunkSize(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+
+	// Without the chunk size, the multiple is not known
+	value, adjustment := roundValue(catalog["INNODB_BUFFER_POOL_SIZE"], "1500000000", map[string]any{})
+	require.Equal(t, "1500000000", value)
+	require.Empty(t, adjustment)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// The command that saves a snapshot of the variables of a server.
+const commandSnapshot = "snapshot"
+
+// The extension of snapshot files. A snapshot file given in place of
+// <server:port> is recognized by it.
+const snapshotFileExtension = ".json"
+
+// variableSource is where the version and global variables of a server are
+// read from: either the server itself (dbConn), or a snapshot of it taken
+// earlier (Snapshot).
+type variableSource interface {
+	getVersion() (MySQLVersion, error)
+	getVariables() (map[string]any, error)
+	getVariableSources() (map[string]*VariableSource, error)
+}
+
+// Snapshot is the version, global variables and variable sources of a
+// server at a point in time, so that a config can be compared with the
+// server without connecting to it.
+type Snapshot struct {
+	Server  string    `json:"server"`
+	TakenAt time.Time `json:"taken_at"`
+	Version string    `json:"version"`
+	Flavor  Flavor    `json:"flavor,omitempty"`
+	// Variables are keyed by the server variable key.
+	Variables map[string]string `json:"variables"`
+	// VariableSources are only recorded for servers that report them.
+	VariableSources map[string]*VariableSource `json:"variable_sources,omitempty"`
+}
+
+// Reads the version, global variables and variable sources of a server into
+// a snapshot. Failing to read the variable sources is not fatal, like when
+// comparing with the server.
+func takeSnapshot(source variableSource, server string, now time.Time, stderr io.Writer) (*Snapshot, error) {
+	version, err := source.getVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mysql version: %w", err)
+	}
+	serverVariables, err := source.getVariables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query MySQL for server variables: %w", err)
+	}
+	snapshot := &Snapshot{
+		Server:    server,
+		TakenAt:   now.UTC(),
+		Version:   version.String(),
+		Flavor:    version.Flavor,
+		Variables: make(map[string]string, len(serverVariables)),
+	}
+	for key, value := range serverVariables {
+		snapshot.Variables[key] = fmt.Sprint(value)
+	}
+	if version.hasVariablesInfo() {
+		snapshot.VariableSources, err = source.getVariableSources()
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Warning: failed to query MySQL for variable sources: %v\n", err)
+		}
+	}
+	return snapshot, nil
+}
+
+// Writes the snapshot as JSON to the given path.
+func saveSnapshot(snapshot *Snapshot, path string) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Reads a snapshot saved by the snapshot command.
+func readSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if snapshot.Version == "" || snapshot.Variables == nil {
+		return nil, fmt.Errorf("invalid snapshot %s: no version or variables", path)
+	}
+	return &snapshot, nil
+}
+
+// Reports whether the argument given in place of <server:port> is a
+// snapshot file.
+func isSnapshotFile(serverAndPort string) bool {
+	return strings.HasSuffix(serverAndPort, snapshotFileExtension)
+}
+
+func (s *Snapshot) getVersion() (MySQLVersion, error) {
+	version, err := ParseVersion(s.Version)
+	if err != nil {
+		return MySQLVersion{}, err
+	}
+	version.Flavor = s.Flavor
+	return version, nil
+}
+
+func (s *Snapshot) getVariables() (map[string]any, error) {
+	variables := make(map[string]any, len(s.Variables))
+	for key, value := range s.Variables {
+		variables[strings.ToUpper(key)] = value
+	}
+	return variables, nil
+}
+
+func (s *Snapshot) getVariableSources() (map[string]*VariableSource, error) {
+	return s.VariableSources, nil
+}
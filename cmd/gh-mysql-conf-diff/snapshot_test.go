@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeAndReadSnapshot(t *testing.T) {
+	conn, m, err := sqlmock.New()
+	require.NoError(t, err)
+	db := &dbConn{conn: conn}
+	defer db.close()
+
+	m.ExpectQuery("SELECT VERSION\\(\\), @@version_comment").WillReturnRows(
+		sqlmock.NewRows([]string{"VERSION()", "@@version_comment"}).
+			AddRow("8.0.34-26", "Percona Server (GPL), Release 26, Revision 0a4d6e6e"))
+	m.ExpectQuery("SHOW GLOBAL VARIABLES").WillReturnRows(
+		sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("max_connections", "500").
+			AddRow("datadir", "/var/lib/mysql/"))
+	m.ExpectQuery("SELECT VARIABLE_NAME, VARIABLE_SOURCE").WillReturnRows(
+		sqlmock.NewRows([]string{"VARIABLE_NAME", "VARIABLE_SOURCE", "VARIABLE_PATH", "SET_TIME", "SET_USER", "SET_HOST"}).
+			AddRow("max_connections", "EXPLICIT", "/etc/my.cnf", nil, nil, nil))
+
+	now := time.Date(2024, 1, 2, 3, 12, 0, 0, time.UTC)
+	snapshot, err := takeSnapshot(db, "db1:3306", now, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.NoError(t, m.ExpectationsWereMet())
+
+	path := filepath.Join(t.TempDir(), "db1.json")
+	require.NoError(t, saveSnapshot(snapshot, path))
+	read, err := readSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, snapshot, read)
+
+	// The snapshot reads back like the server it was taken from
+	version, err := read.getVersion()
+	require.NoError(t, err)
+	require.Equal(t, MySQLVersion{Major: 8, Minor: 0, Patch: 34, Flavor: FlavorPercona}, version)
+	variables, err := read.getVariables()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"MAX_CONNECTIONS": "500", "DATADIR": "/var/lib/mysql/"}, variables)
+	sources, err := read.getVariableSources()
+	require.NoError(t, err)
+	require.Equal(t, "EXPLICIT /etc/my.cnf", sources["MAX_CONNECTIONS"].String())
+}
+
+func TestReadSnapshotInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "empty.json"), `{}`)
+	writeTestFile(t, filepath.Join(dir, "broken.json"), `{"version":`)
+
+	_, err := readSnapshot(filepath.Join(dir, "empty.json"))
+	require.ErrorContains(t, err, "no version or variables")
+	_, err = readSnapshot(filepath.Join(dir, "broken.json"))
+	require.ErrorContains(t, err, "invalid snapshot")
+	_, err = readSnapshot(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
+
+func TestDiffAgainstSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "my.cnf"), `
+[mysqld]
+max_connections = 1000
+datadir = /var/lib/mysql
+`)
+	snapshot := &Snapshot{
+		Server:    "db1:3306",
+		Version:   "8.0.36",
+		Variables: map[string]string{"MAX_CONNECTIONS": "500", "DATADIR": "/var/lib/mysql/"},
+	}
+	context := &RunContext{configPath: filepath.Join(dir, "my.cnf")}
+
+	input, err := getOptionsFrom(context, snapshot, &bytes.Buffer{})
+	require.NoError(t, err)
+	confOptions := limitToWatchedOptions(input.confOptions, input.serverVariables)
+	results := mysqlConfDiff(confOptions, input.serverVariables, input.confSources,
+		input.serverSources, input.catalog, orderByName)
+
+	require.Equal(t, "DATADIR", results[0].Key)
+	require.Equal(t, StatusEqual, results[0].Status)
+	require.Equal(t, "MAX_CONNECTIONS", results[1].Key)
+	require.Equal(t, StatusDifferent, results[1].Status)
+	require.Equal(t, "500", results[1].ServerValue)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The --emit-sql value that writes the SQL script to stdout.
+const emitSQLToStdout = "-"
+
+// Writes the SET statements that --apply-changes would run for the
+// differences in the report as a SQL script, so they can be reviewed before
+// being run. Each statement is preceded by a comment with the current
+// server value and where the config value was set.
+func writeSQLScript(report *DiffReport, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "-- Generated by %s for %s (%s %s)\n",
+		getBinaryName(), report.Server, report.Flavor, report.Version)
+	if err != nil {
+		return err
+	}
+	for _, result := range report.Options {
+		if result.Status != StatusDifferent {
+			continue
+		}
+		applyValue, rollbackValue := result.changeValues()
+		_, err = fmt.Fprintf(w, "\n%s\n", formatSQLComment(
+			fmt.Sprintf("%s: was %s, my.cnf %s%s",
+				result.Key, rollbackValue, applyValue, describeSource(result.ConfigSource))))
+		if err != nil {
+			return err
+		}
+		statement, err := formatSetStatement(result.Key, applyValue, report.ApplyMode, result.Metadata)
+		if err != nil {
+			// Leave a note for the reviewer instead of an invalid statement
+			statement = formatSQLComment(fmt.Sprintf("Skipped: %v", err))
+		}
+		_, err = fmt.Fprintf(w, "%s\n", statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Formats text as a SQL comment, keeping any line breaks in the text from
+// ending the comment.
+func formatSQLComment(text string) string {
+	return "-- " + strings.ReplaceAll(text, "\n", "\n-- ")
+}
+
+// A SET or RESET PERSIST statement read back from a SQL script written by
+// this program.
+type sqlSetting struct {
+	Mode applyMode
+	Key  string
+	// Value is an int, a float64 or a string, as written in the script.
+	Value any
+	// Reset is set for RESET PERSIST statements, which have no mode or
+	// value.
+	Reset bool
+	Line  int
+}
+
+// Returns the name of the statement, for messages.
+func (s sqlSetting) statementName() string {
+	if s.Reset {
+		return "RESET PERSIST"
+	}
+	return "SET"
+}
+
+// Match the statements written by formatSetStatement and
+// formatResetPersistStatement.
+var (
+	setStatementPattern   = regexp.MustCompile(`^SET (\S+) (\S+) = (.*);$`)
+	resetStatementPattern = regexp.MustCompile(`^RESET PERSIST IF EXISTS (\S+);$`)
+)
+
+// Reads the SET and RESET PERSIST statements from a SQL script written by
+// this program, in order. Comments and blank lines are skipped, while any other
+// SQL is rejected, since the statements are replayed through applySetting
+// rather than run as is.
+func parseSQLScript(r io.Reader) ([]sqlSetting, error) {
+	var settings []sqlSetting
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		if match := resetStatementPattern.FindStringSubmatch(line); match != nil {
+			settings = append(settings, sqlSetting{Key: match[1], Reset: true, Line: lineNumber})
+			continue
+		}
+		match := setStatementPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: not a SET statement: %s", lineNumber, line)
+		}
+		mode, err := applyModeFromKeyword(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		value, err := parseSQLLiteral(match[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		settings = append(settings, sqlSetting{Mode: mode, Key: match[2], Value: value, Line: lineNumber})
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Parses a SQL literal as formatted by formatSQLLiteral: an integer, a
+// float or a quoted string.
+func parseSQLLiteral(literal string) (any, error) {
+	if valueInt, err := strconv.Atoi(literal); err == nil {
+		return valueInt, nil
+	}
+	if valueFloat, err := strconv.ParseFloat(literal, 64); err == nil {
+		return valueFloat, nil
+	}
+	if len(literal) < 2 || literal[0] != '\'' || literal[len(literal)-1] != '\'' {
+		return "", fmt.Errorf("invalid value: %s", literal)
+	}
+	var value strings.Builder
+	quoted := literal[1 : len(literal)-1]
+	for i := 0; i < len(quoted); i++ {
+		c := quoted[i]
+		switch {
+		case c == '\\' && i+1 < len(quoted):
+			i++
+			value.WriteString(unescapeSQLCharacter(quoted[i]))
+		case c == '\'' && i+1 < len(quoted) && quoted[i+1] == '\'':
+			i++
+			value.WriteByte('\'')
+		case c == '\\' || c == '\'':
+			return "", fmt.Errorf("invalid value: %s", literal)
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return value.String(), nil
+}
+
+// Returns the character for an escape sequence in a quoted SQL string,
+// e.g. `n` for `\n`.
+func unescapeSQLCharacter(c byte) string {
+	switch c {
+	case '0':
+		return "\x00"
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 'Z':
+		return "\x1a"
+	default:
+		return string(c)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSQLScript(t *testing.T) {
+	report := &DiffReport{
+		Server:  "localhost:3306",
+		Version: "8.0.28",
+		Options: []*OptionResult{
+			{
+				Key: "CONNECT_TIMEOUT", ConfigValue: "60", ServerValue: "30", Status: StatusDifferent,
+				ConfigSource: &OptionSource{
+					Name: "connect_timeout", Value: "60", File: "/etc/my.cnf", Line: 2, Section: "mysqld",
+				},
+			},
+			{Key: "MAX_CONNECTIONS", ConfigValue: "151", ServerValue: "151", Status: StatusEqual},
+			{Key: "INIT_CONNECT", ConfigValue: "SET NAMES 'utf8mb4'", ServerValue: "", Status: StatusDifferent},
+			{Key: "BAD KEY", ConfigValue: "1", ServerValue: "2", Status: StatusDifferent},
+		},
+	}
+
+	script := bytes.Buffer{}
+	err := writeSQLScript(report, &script)
+	require.NoError(t, err)
+
+	expected := "-- Generated by " + getBinaryName() + " for localhost:3306 (MySQL 8.0.28)\n" +
+		"\n" +
+		"-- CONNECT_TIMEOUT: was 30, my.cnf 60 (/etc/my.cnf:2 [mysqld])\n" +
+		"SET GLOBAL CONNECT_TIMEOUT = 60;\n" +
+		"\n" +
+		"-- INIT_CONNECT: was , my.cnf SET NAMES 'utf8mb4'\n" +
+		"SET GLOBAL INIT_CONNECT = 'SET NAMES \\'utf8mb4\\'';\n" +
+		"\n" +
+		"-- BAD KEY: was 2, my.cnf 1\n" +
+		"-- Skipped: invalid key: BAD KEY\n"
+	require.Equal(t, expected, script.String())
+}
+
+func TestParseSQLScript(t *testing.T) {
+	values := []any{30, "", "SET NAMES 'utf8mb4'", `C:\data\`, "a\nb", -1, 2.5}
+	script := bytes.Buffer{}
+	for _, value := range values {
+		statement, err := formatSetStatement("INIT_CONNECT", value, applyModeGlobal, nil)
+		require.NoError(t, err)
+		script.WriteString("-- comment\n" + statement + "\n")
+	}
+
+	// The values survive a round trip through the script
+	settings, err := parseSQLScript(&script)
+	require.NoError(t, err)
+	require.Len(t, settings, len(values))
+	for i, setting := range settings {
+		require.Equal(t, "INIT_CONNECT", setting.Key)
+		require.Equal(t, values[i], setting.Value)
+		require.Equal(t, 2*i+2, setting.Line)
+	}
+}
+
+func TestParseSQLScriptApplyModes(t *testing.T) {
+	settings, err := parseSQLScript(strings.NewReader(
+		"SET PERSIST MAX_CONNECTIONS = 151;\nSET PERSIST_ONLY INNODB_LOG_FILE_SIZE = 50331648;\n" +
+			"RESET PERSIST IF EXISTS SORT_BUFFER_SIZE;\n"))
+	require.NoError(t, err)
+	require.Equal(t, []sqlSetting{
+		{Mode: applyModePersist, Key: "MAX_CONNECTIONS", Value: 151, Line: 1},
+		{Mode: applyModePersistOnly, Key: "INNODB_LOG_FILE_SIZE", Value: 50331648, Line: 2},
+		{Key: "SORT_BUFFER_SIZE", Reset: true, Line: 3},
+	}, settings)
+
+	_, err = parseSQLScript(strings.NewReader("SET SESSION MAX_CONNECTIONS = 151;\n"))
+	require.EqualError(t, err, "line 1: unknown SET keyword: SESSION")
+}
+
+func TestParseSQLLiteral(t *testing.T) {
+	value, err := parseSQLLiteral(`'it''s'`)
+	require.NoError(t, err)
+	require.Equal(t, "it's", value)
+
+	_, err = parseSQLLiteral(`'a'; DROP TABLE users; SELECT 'b'`)
+	require.Error(t, err)
+
+	_, err = parseSQLLiteral("ON")
+	require.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// valueComparator reports whether a my.cnf option value is equal to a
+// server variable value.
+type valueComparator func(optionValue, serverValue string) bool
+
+// The comparators for each variable type. Variables of other types, and
+// variables without metadata, are compared with isEqualValue.
+var typeComparators = map[VariableType]valueComparator{
+	TypeBool:  isEqualBool,
+	TypeInt:   isEqualInteger,
+	TypeSize:  isEqualInteger,
+	TypeFloat: isEqualFloat,
+	TypeEnum:  isEqualEnum,
+	TypeSet:   isEqualSet,
+	TypeFlags: isEqualFlags,
+	TypePath:  isEqualValue,
+}
+
+// Returns the comparator for a variable, based on its metadata.
+func comparatorFor(metadata *VariableMetadata) valueComparator {
+	if metadata == nil {
+		return isEqualValue
+	}
+	if comparator, ok := typeComparators[metadata.Type]; ok {
+		return comparator
+	}
+	return isEqualValue
+}
+
+// Reports whether a my.cnf option value is equal to a server variable
+// value, using the comparator for the variable.
+func compareValues(metadata *VariableMetadata, optionValue, serverValue string) bool {
+	return comparatorFor(metadata)(optionValue, serverValue)
+}
+
+// Returns the values that change a variable from the server value to the
+// option value, and back. These are the values themselves, except for flag
+// lists, where only the flags that differ are set, e.g.
+// `SET GLOBAL optimizer_switch='index_merge=off'`.
+func changeValues(metadata *VariableMetadata, optionValue, serverValue string) (apply, rollback string) {
+	if metadata != nil && metadata.Type == TypeFlags {
+		optionFlags, serverFlags := diffFlags(optionValue, serverValue)
+		return formatFlags(optionFlags), formatFlags(serverFlags)
+	}
+	return optionValue, serverValue
+}
+
+// Compares boolean values, which mysqld accepts as ON, TRUE, YES or 1 and
+// OFF, FALSE, NO or 0, in any case.
+func isEqualBool(optionValue, serverValue string) bool {
+	option, optionOk := parseBool(optionValue)
+	server, serverOk := parseBool(serverValue)
+	if !optionOk || !serverOk {
+		return isEqualValue(optionValue, serverValue)
+	}
+	return option == server
+}
+
+// Parses a boolean value the way mysqld does, and reports whether it is
+// one.
+func parseBool(value string) (bool, bool) {
+	switch strings.ToUpper(value) {
+	case "ON", "TRUE", "YES", "1":
+		return true, true
+	case "OFF", "FALSE", "NO", "0":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Compares integers by value, so that e.g. `0010` equals `10`. Unsigned
+// 64-bit values are compared exactly, e.g. the 18446744073709551615 that
+// max_binlog_cache_size defaults to, which a float can't represent.
+func isEqualInteger(optionValue, serverValue string) bool {
+	option, optionOk := parseInteger(optionValue)
+	server, serverOk := parseInteger(serverValue)
+	if !optionOk || !serverOk {
+		return isEqualValue(optionValue, serverValue)
+	}
+	return option == server
+}
+
+// Parses a signed or unsigned 64-bit integer, and returns it in canonical
+// form, and whether it is one.
+func parseInteger(value string) (string, bool) {
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return strconv.FormatInt(number, 10), true
+	}
+	if number, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return strconv.FormatUint(number, 10), true
+	}
+	return "", false
+}
+
+// Compares floats by value, so that e.g. `2` equals `2.000000`, which is
+// how the server reports long_query_time.
+func isEqualFloat(optionValue, serverValue string) bool {
+	option, err := strconv.ParseFloat(optionValue, 64)
+	if err != nil {
+		return isEqualValue(optionValue, serverValue)
+	}
+	server, err := strconv.ParseFloat(serverValue, 64)
+	if err != nil {
+		return isEqualValue(optionValue, serverValue)
+	}
+	return option == server
+}
+
+// Compares enum values, which mysqld matches regardless of case, e.g. `row`
+// and `ROW` for binlog_format.
+func isEqualEnum(optionValue, serverValue string) bool {
+	return strings.EqualFold(optionValue, serverValue)
+}
+
+// Compares set values as sets of members, regardless of their order and
+// case, e.g. the modes of sql_mode.
+func isEqualSet(optionValue, serverValue string) bool {
+	option := parseSet(optionValue)
+	server := parseSet(serverValue)
+	if len(option) != len(server) {
+		return false
+	}
+	for member := range option {
+		if !server[member] {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses the members of a comma-separated set value.
+func parseSet(value string) map[string]bool {
+	members := make(map[string]bool)
+	for _, member := range strings.Split(value, ",") {
+		member = strings.ToUpper(strings.TrimSpace(member))
+		if member != "" {
+			members[member] = true
+		}
+	}
+	return members
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareValues(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+
+	tests := []struct {
+		name        string
+		key         string
+		optionValue string
+		serverValue string
+		expected    bool
+	}{
+		{"when a bool is TRUE", "SLOW_QUERY_LOG", "TRUE", "ON", true},
+		{"when a bool is yes", "SLOW_QUERY_LOG", "yes", "ON", true},
+		{"when a bool is on", "SLOW_QUERY_LOG", "on", "ON", true},
+		{"when a bool is 0", "SLOW_QUERY_LOG", "0", "OFF", true},
+		{"when a bool differs", "SLOW_QUERY_LOG", "false", "ON", false},
+		{"when a float has no decimals", "LONG_QUERY_TIME", "2", "2.000000", true},
+		{"when a float differs", "LONG_QUERY_TIME", "2.5", "2.000000", false},
+		{"when a size is the same", "SORT_BUFFER_SIZE", "262144", "262144", true},
+		{"when an int has leading zeros", "MAX_CONNECTIONS", "0151", "151", true},
+		{
+			"when a size is the largest unsigned value", "TMP_TABLE_SIZE",
+			"18446744073709551615", "18446744073709551615", true,
+		},
+		{
+			"when a size differs by less than a float can tell", "TMP_TABLE_SIZE",
+			"18446744073709551615", "18446744073709551614", false,
+		},
+		{"when an enum differs in case", "BINLOG_FORMAT", "row", "ROW", true},
+		{"when an enum differs", "BINLOG_FORMAT", "MIXED", "ROW", false},
+		{
+			"when set members are in a different order", "SQL_MODE",
+			"STRICT_TRANS_TABLES, no_engine_substitution", "NO_ENGINE_SUBSTITUTION,STRICT_TRANS_TABLES", true,
+		},
+		{"when set members differ", "SQL_MODE", "STRICT_TRANS_TABLES", "NO_ENGINE_SUBSTITUTION,STRICT_TRANS_TABLES", false},
+		{"when a set is empty", "SQL_MODE", "", "", true},
+		{"when a path has a trailing slash", "DATADIR", "/var/lib/mysql", "/var/lib/mysql/", true},
+		{"when a string differs in case", "TIME_ZONE", "system", "SYSTEM", false},
+		{"when the set flags match", "OPTIMIZER_SWITCH", "mrr=on", "index_merge=on,mrr=on", true},
+		{"when a set flag differs", "OPTIMIZER_SWITCH", "mrr=off", "index_merge=on,mrr=on", false},
+		{"when the variable is unknown", "NO_SUCH_VARIABLE", "1", "ON", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, compareValues(catalog[tt.key], tt.optionValue, tt.serverValue))
+		})
+	}
+}
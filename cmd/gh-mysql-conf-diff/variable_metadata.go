@@ -0,0 +1,400 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The metadata of the MySQL system variables the tool knows about, covering
+// MySQL 5.7, 8.0, 8.4 and 9.x, and Percona Server, which shares them. Each
+// variable is keyed by its name and has:
+//
+//   - scope: global, session or both
+//   - dynamic: whether it can be changed at runtime
+//   - type: bool, int, enum, set, size, path, float, flags (lists of
+//     name=value flags, e.g. optimizer_switch) or string
+//   - values: the allowed values of enum and set variables
+//   - min and max: the range of numeric variables
+//   - block_size: the multiple the server rounds int and size values down
+//     to, if any
+//   - default: the compiled-in default value
+//   - introduced, deprecated and removed: the versions these happened in
+//   - mariadb: whether MariaDB shares the scope, dynamic, type and values
+//     of the variable, so that these apply to it too
+//
+// dynamic and default may also be an object mapping the version a value
+// applies from to the value, e.g. `{"5.7": "latin1", "8.0": "utf8mb4"}`.
+// To support a new release, add or update the variables that changed in
+// it; the catalog is checked by the tests.
+//
+//go:embed system_variables.json
+var systemVariablesData []byte
+
+// The catalog of system variable metadata, loaded from the embedded data.
+var systemVariables = mustLoadMetadataCatalog(systemVariablesData)
+
+// VariableType is the type of the value of a system variable.
+type VariableType string
+
+// The types of system variables.
+const (
+	TypeBool   VariableType = "bool"
+	TypeInt    VariableType = "int"
+	TypeEnum   VariableType = "enum"
+	TypeSet    VariableType = "set"
+	TypeSize   VariableType = "size"
+	TypePath   VariableType = "path"
+	TypeFloat  VariableType = "float"
+	TypeFlags  VariableType = "flags"
+	TypeString VariableType = "string"
+)
+
+var variableTypes = []VariableType{
+	TypeBool, TypeInt, TypeEnum, TypeSet, TypeSize, TypePath, TypeFloat, TypeFlags, TypeString,
+}
+
+// VariableScope is whether a system variable has a global value, a session
+// value or both.
+type VariableScope string
+
+// The scopes of system variables.
+const (
+	ScopeGlobal  VariableScope = "global"
+	ScopeSession VariableScope = "session"
+	ScopeBoth    VariableScope = "both"
+)
+
+// VariableMetadata describes a system variable as of a given MySQL version.
+type VariableMetadata struct {
+	Name    string
+	Scope   VariableScope
+	Dynamic bool
+	Type    VariableType
+	// Values are the allowed values of enum and set variables.
+	Values []string
+	// Min and Max are the range of numeric variables, if known.
+	Min *float64
+	Max *float64
+	// BlockSize is the multiple the server rounds int and size values down
+	// to, if any.
+	BlockSize uint64
+	// Default is the compiled-in default value, if known.
+	Default string
+	// Introduced is the version the variable was added in, if known.
+	Introduced string
+	// Deprecated and Removed are the versions the variable was deprecated
+	// and removed in, if the server version is at or after them.
+	Deprecated string
+	Removed    string
+}
+
+// VariableCatalog is the metadata of the system variables as of a given
+// MySQL version, keyed by the server variable key (see GetVariableKeyFrom).
+type VariableCatalog map[string]*VariableMetadata
+
+// MetadataCatalog is the metadata of the system variables across all the
+// MySQL versions it covers.
+type MetadataCatalog struct {
+	definitions map[string]*variableDefinition
+}
+
+// The definition of a system variable in the catalog data.
+type variableDefinition struct {
+	Scope      VariableScope     `json:"scope"`
+	Dynamic    versioned[bool]   `json:"dynamic"`
+	Type       VariableType      `json:"type"`
+	Values     []string          `json:"values"`
+	Min        *float64          `json:"min"`
+	Max        *float64          `json:"max"`
+	BlockSize  uint64            `json:"block_size"`
+	Default    versioned[string] `json:"default"`
+	Introduced string            `json:"introduced"`
+	Deprecated string            `json:"deprecated"`
+	Removed    string            `json:"removed"`
+	MariaDB    bool              `json:"mariadb"`
+}
+
+// A value that may change between versions. In the catalog data, it is
+// either a plain value that applies to all versions, or an object mapping
+// the version a value applies from to the value.
+type versioned[T any] struct {
+	since  []MySQLVersion
+	values []T
+}
+
+func (v *versioned[T]) UnmarshalJSON(data []byte) error {
+	var value T
+	if err := json.Unmarshal(data, &value); err == nil {
+		v.since = []MySQLVersion{{}}
+		v.values = []T{value}
+		return nil
+	}
+	var byVersion map[string]T
+	if err := json.Unmarshal(data, &byVersion); err != nil {
+		return err
+	}
+	type entry struct {
+		since MySQLVersion
+		value T
+	}
+	entries := make([]entry, 0, len(byVersion))
+	for since, value := range byVersion {
+		version, err := parseCatalogVersion(since)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{version, value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].since.Compare(entries[j].since) < 0 })
+	for _, entry := range entries {
+		v.since = append(v.since, entry.since)
+		v.values = append(v.values, entry.value)
+	}
+	return nil
+}
+
+// Returns the value that applies to the given version, and whether there
+// is one.
+func (v *versioned[T]) at(version MySQLVersion) (T, bool) {
+	var value T
+	found := false
+	for i, since := range v.since {
+		if since.Compare(version) > 0 {
+			break
+		}
+		value, found = v.values[i], true
+	}
+	return value, found
+}
+
+// Parses a version in the catalog data, either MAJOR.MINOR or
+// MAJOR.MINOR.PATCH.
+func parseCatalogVersion(version string) (MySQLVersion, error) {
+	if strings.Count(version, ".") == 1 {
+		version += ".0"
+	}
+	parsed, err := ParseVersion(version)
+	if err != nil {
+		return MySQLVersion{}, fmt.Errorf("invalid catalog version %q: %w", version, err)
+	}
+	return parsed, nil
+}
+
+// Loads the catalog from the embedded data, and panics if the data is
+// invalid, which the tests catch.
+func mustLoadMetadataCatalog(data []byte) *MetadataCatalog {
+	catalog, err := NewMetadataCatalog(data)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// NewMetadataCatalog creates a catalog from the JSON catalog data and
+// validates it.
+func NewMetadataCatalog(data []byte) (*MetadataCatalog, error) {
+	var definitions map[string]*variableDefinition
+	err := json.Unmarshal(data, &definitions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variable metadata: %w", err)
+	}
+	for name, definition := range definitions {
+		err = definition.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid variable metadata for %s: %w", name, err)
+		}
+	}
+	return &MetadataCatalog{definitions: definitions}, nil
+}
+
+// Checks that the definition has a known scope and type, and valid
+// versions.
+func (d *variableDefinition) validate() error {
+	if d.Scope != ScopeGlobal && d.Scope != ScopeSession && d.Scope != ScopeBoth {
+		return fmt.Errorf("unknown scope: %q", d.Scope)
+	}
+	known := false
+	for _, variableType := range variableTypes {
+		known = known || d.Type == variableType
+	}
+	if !known {
+		return fmt.Errorf("unknown type: %q", d.Type)
+	}
+	if (d.Type == TypeEnum || d.Type == TypeSet) != (len(d.Values) > 0) {
+		return fmt.Errorf("values must be given for enum and set types only")
+	}
+	if d.BlockSize != 0 && d.Type != TypeInt && d.Type != TypeSize {
+		return fmt.Errorf("block_size must be given for int and size types only")
+	}
+	if len(d.Dynamic.since) == 0 {
+		return fmt.Errorf("dynamic must be given")
+	}
+	// MariaDB versions can't be compared with MySQL versions
+	if d.MariaDB && len(d.Dynamic.since) > 1 {
+		return fmt.Errorf("dynamic must not depend on the version for variables shared with mariadb")
+	}
+	for _, version := range []string{d.Introduced, d.Deprecated, d.Removed} {
+		if version == "" {
+			continue
+		}
+		if _, err := parseCatalogVersion(version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForVersion returns the metadata of the system variables as of the given
+// MySQL version. Variables introduced after the version are left out,
+// while removed variables are kept, with Removed set, so that their use
+// can be reported. MariaDB has its own variables and version numbers, so
+// only the variables it shares with MySQL are kept for it (see
+// forMariaDB).
+func (c *MetadataCatalog) ForVersion(version MySQLVersion) VariableCatalog {
+	if version.Flavor == FlavorMariaDB {
+		return c.forMariaDB()
+	}
+	catalog := make(VariableCatalog)
+	for name, definition := range c.definitions {
+		if definition.Introduced != "" && !reachedVersion(version, definition.Introduced) {
+			continue
+		}
+		metadata := &VariableMetadata{
+			Name:       name,
+			Scope:      definition.Scope,
+			Type:       definition.Type,
+			Values:     definition.Values,
+			Min:        definition.Min,
+			Max:        definition.Max,
+			BlockSize:  definition.BlockSize,
+			Introduced: definition.Introduced,
+		}
+		metadata.Dynamic, _ = definition.Dynamic.at(version)
+		metadata.Default, _ = definition.Default.at(version)
+		if definition.Deprecated != "" && reachedVersion(version, definition.Deprecated) {
+			metadata.Deprecated = definition.Deprecated
+		}
+		if definition.Removed != "" && reachedVersion(version, definition.Removed) {
+			metadata.Removed = definition.Removed
+		}
+		catalog[GetVariableKeyFrom(name)] = metadata
+	}
+	return catalog
+}
+
+// Returns the metadata of the variables that MariaDB shares with MySQL.
+// Only their scope, whether they are dynamic, type and values are known:
+// the ranges, defaults and versions in the catalog are MySQL's, so they
+// are left out, and the values of other variables are compared as they
+// are.
+func (c *MetadataCatalog) forMariaDB() VariableCatalog {
+	catalog := make(VariableCatalog)
+	for name, definition := range c.definitions {
+		if !definition.MariaDB {
+			continue
+		}
+		catalog[GetVariableKeyFrom(name)] = &VariableMetadata{
+			Name:    name,
+			Scope:   definition.Scope,
+			Dynamic: definition.Dynamic.values[0],
+			Type:    definition.Type,
+			Values:  definition.Values,
+		}
+	}
+	return catalog
+}
+
+// Returns the notes on the metadata available for the given version, for
+// the report.
+func metadataNotes(version MySQLVersion) []string {
+	if version.Flavor != FlavorMariaDB {
+		return nil
+	}
+	return []string{"MariaDB metadata is unavailable: only the variables shared with MySQL " +
+		"are compared by type, without checking ranges, defaults or deprecations"}
+}
+
+// Reports whether the version is at or after the catalog version.
+func reachedVersion(version MySQLVersion, catalogVersion string) bool {
+	since, err := parseCatalogVersion(catalogVersion)
+	return err == nil && version.Compare(since) >= 0
+}
+
+// Converts a config value to the type the variable expects in a SET
+// statement: integers for int and size variables, floats for float
+// variables and strings otherwise. Without metadata, integers are sent as
+// integers and anything else as strings.
+func (m *VariableMetadata) typedValue(value string) any {
+	if m != nil && m.Type == TypeFloat {
+		if valueFloat, err := strconv.ParseFloat(value, 64); err == nil {
+			return valueFloat
+		}
+		return value
+	}
+	if m == nil || m.Type == TypeInt || m.Type == TypeSize || m.Type == TypeBool {
+		if valueInt, err := strconv.Atoi(value); err == nil {
+			return valueInt
+		}
+	}
+	return value
+}
+
+// Checks a config value against the metadata of the variable, and returns
+// notes on anything that looks wrong: the variable being deprecated or
+// removed, a number out of range, or a value that is not allowed.
+func (m *VariableMetadata) lint(value string) []string {
+	if m == nil {
+		return nil
+	}
+	var notes []string
+	if m.Removed != "" {
+		notes = append(notes, fmt.Sprintf("removed in MySQL %s", m.Removed))
+	} else if m.Deprecated != "" {
+		notes = append(notes, fmt.Sprintf("deprecated since MySQL %s", m.Deprecated))
+	}
+	switch m.Type {
+	case TypeInt, TypeSize, TypeFloat:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("value %s is not a number", value))
+			break
+		}
+		if m.Min != nil && number < *m.Min {
+			notes = append(notes, fmt.Sprintf("value %s is below the minimum of %s", value, formatNumber(*m.Min)))
+		}
+		if m.Max != nil && number > *m.Max {
+			notes = append(notes, fmt.Sprintf("value %s is above the maximum of %s", value, formatNumber(*m.Max)))
+		}
+	case TypeEnum:
+		if !m.allows(value) {
+			notes = append(notes, fmt.Sprintf("value %s is not one of %s", value, strings.Join(m.Values, ", ")))
+		}
+	case TypeSet:
+		for _, member := range strings.Split(value, ",") {
+			if member != "" && !m.allows(member) {
+				notes = append(notes, fmt.Sprintf("value %s is not one of %s", member, strings.Join(m.Values, ", ")))
+			}
+		}
+	}
+	return notes
+}
+
+// Reports whether the value is one of the allowed values of an enum or set
+// variable. MySQL matches these case-insensitively.
+func (m *VariableMetadata) allows(value string) bool {
+	for _, allowed := range m.Values {
+		if strings.EqualFold(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats a number from the catalog without an exponent.
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
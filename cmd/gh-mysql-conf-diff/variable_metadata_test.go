@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemVariablesCatalog(t *testing.T) {
+	_, err := NewMetadataCatalog(systemVariablesData)
+	require.NoError(t, err)
+
+	for _, version := range []MySQLVersion{
+		{Major: 5, Minor: 7, Patch: 44},
+		{Major: 8, Minor: 0, Patch: 36},
+		{Major: 8, Minor: 4, Patch: 0},
+		{Major: 9, Minor: 1, Patch: 0},
+	} {
+		catalog := systemVariables.ForVersion(version)
+		metadata := catalog["MAX_CONNECTIONS"]
+		require.NotNil(t, metadata, version.String())
+		assert.Equal(t, "max_connections", metadata.Name)
+		assert.Equal(t, ScopeGlobal, metadata.Scope)
+		assert.True(t, metadata.Dynamic)
+		assert.Equal(t, TypeInt, metadata.Type)
+		assert.Equal(t, "151", metadata.Default)
+	}
+}
+
+func TestMetadataCatalogForVersion(t *testing.T) {
+	mysql57 := systemVariables.ForVersion(MySQLVersion{Major: 5, Minor: 7, Patch: 44})
+	mysql80 := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 29})
+	mysql8030 := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 30})
+	mysql84 := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 4, Patch: 0})
+
+	// Versioned defaults and dynamic flags
+	assert.Equal(t, "latin1", mysql57["CHARACTER_SET_SERVER"].Default)
+	assert.Equal(t, "utf8mb4", mysql80["CHARACTER_SET_SERVER"].Default)
+	assert.False(t, mysql57["INNODB_LOG_BUFFER_SIZE"].Dynamic)
+	assert.True(t, mysql80["INNODB_LOG_BUFFER_SIZE"].Dynamic)
+
+	// Variables introduced later are left out
+	assert.Nil(t, mysql80["INNODB_REDO_LOG_CAPACITY"])
+	assert.NotNil(t, mysql8030["INNODB_REDO_LOG_CAPACITY"])
+
+	// Deprecated and removed variables are kept, and flagged
+	assert.Empty(t, mysql80["INNODB_LOG_FILE_SIZE"].Deprecated)
+	assert.Equal(t, "8.0.30", mysql8030["INNODB_LOG_FILE_SIZE"].Deprecated)
+	assert.Equal(t, "8.0.3", mysql80["EXPIRE_LOGS_DAYS"].Deprecated)
+	assert.Empty(t, mysql80["EXPIRE_LOGS_DAYS"].Removed)
+	assert.Equal(t, "8.4.0", mysql84["EXPIRE_LOGS_DAYS"].Removed)
+
+	// Percona Server shares the MySQL metadata, while MariaDB only has the
+	// variables it shares with MySQL, without their MySQL ranges and
+	// versions
+	perconaVersion := MySQLVersion{Major: 8, Minor: 0, Patch: 34, Flavor: FlavorPercona}
+	percona := systemVariables.ForVersion(perconaVersion)
+	assert.NotNil(t, percona["MAX_CONNECTIONS"])
+	assert.Empty(t, metadataNotes(perconaVersion))
+	mariaDB := MySQLVersion{Major: 10, Minor: 6, Patch: 12, Flavor: FlavorMariaDB}
+	mariaDBCatalog := systemVariables.ForVersion(mariaDB)
+	assert.Equal(t, &VariableMetadata{
+		Name: "max_connections", Scope: ScopeGlobal, Dynamic: true, Type: TypeInt,
+	}, mariaDBCatalog["MAX_CONNECTIONS"])
+	assert.Equal(t, "", mariaDBCatalog["BINLOG_FORMAT"].Deprecated)
+	assert.Nil(t, mariaDBCatalog["GTID_MODE"])
+	assert.Nil(t, mariaDBCatalog["INNODB_LOG_FILE_SIZE"])
+	assert.Len(t, metadataNotes(mariaDB), 1)
+}
+
+func TestNewMetadataCatalogInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"when the scope is unknown", `{"a": {"scope": "local", "dynamic": true, "type": "int"}}`,
+			`invalid variable metadata for a: unknown scope: "local"`},
+		{"when the type is unknown", `{"a": {"scope": "global", "dynamic": true, "type": "long"}}`,
+			`invalid variable metadata for a: unknown type: "long"`},
+		{"when an enum has no values", `{"a": {"scope": "global", "dynamic": true, "type": "enum"}}`,
+			"invalid variable metadata for a: values must be given for enum and set types only"},
+		{"when dynamic is missing", `{"a": {"scope": "global", "type": "int"}}`,
+			"invalid variable metadata for a: dynamic must be given"},
+		{
+			"when dynamic depends on the version for mariadb",
+			`{"a": {"scope": "global", "dynamic": {"5.7": false, "8.0": true}, "type": "int", "mariadb": true}}`,
+			"invalid variable metadata for a: dynamic must not depend on the version for variables shared with mariadb",
+		},
+		{"when a version is invalid", `{"a": {"scope": "global", "dynamic": true, "type": "int", "removed": "8"}}`,
+			`invalid variable metadata for a: invalid catalog version "8": invalid version format`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMetadataCatalog([]byte(tt.data))
+			require.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestVariableMetadataTypedValue(t *testing.T) {
+	catalog := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+
+	assert.Equal(t, 1000, catalog["MAX_CONNECTIONS"].typedValue("1000"))
+	assert.Equal(t, 2.5, catalog["LONG_QUERY_TIME"].typedValue("2.5"))
+	assert.Equal(t, "ON", catalog["SLOW_QUERY_LOG"].typedValue("ON"))
+	assert.Equal(t, 1, catalog["SLOW_QUERY_LOG"].typedValue("1"))
+	// A numeric string stays a string for string variables
+	assert.Equal(t, "8", catalog["REPORT_HOST"].typedValue("8"))
+	// Without metadata, integers are guessed
+	var unknown *VariableMetadata
+	assert.Equal(t, 8, unknown.typedValue("8"))
+	assert.Equal(t, "eight", unknown.typedValue("eight"))
+}
+
+func TestVariableMetadataLint(t *testing.T) {
+	mysql80 := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 0, Patch: 36})
+	mysql84 := systemVariables.ForVersion(MySQLVersion{Major: 8, Minor: 4, Patch: 0})
+
+	assert.Empty(t, mysql80["MAX_CONNECTIONS"].lint("1000"))
+	assert.Equal(t, []string{"value 200000 is above the maximum of 100000"},
+		mysql80["MAX_CONNECTIONS"].lint("200000"))
+	assert.Equal(t, []string{"value 1 is below the minimum of 2"}, mysql80["CONNECT_TIMEOUT"].lint("1"))
+	assert.Equal(t, []string{"value lots is not a number"}, mysql80["CONNECT_TIMEOUT"].lint("lots"))
+	assert.Equal(t, []string{"deprecated since MySQL 8.0.3"}, mysql80["EXPIRE_LOGS_DAYS"].lint("7"))
+	assert.Equal(t, []string{"removed in MySQL 8.4.0"}, mysql84["EXPIRE_LOGS_DAYS"].lint("7"))
+	assert.Empty(t, mysql80["BINLOG_ROW_IMAGE"].lint("minimal"))
+	assert.Equal(t, []string{"value SOME is not one of FULL, MINIMAL, NOBLOB"},
+		mysql80["BINLOG_ROW_IMAGE"].lint("SOME"))
+	assert.Equal(t, 1, len(mysql80["SQL_MODE"].lint("STRICT_TRANS_TABLES,NO_SUCH_MODE")))
+
+	var unknown *VariableMetadata
+	assert.Empty(t, unknown.lint("anything"))
+}